@@ -0,0 +1,385 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// AdminMiddleware 管理员权限校验, 需要在登录校验之后使用
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := dao.GetUserByUsername(c.Request.Context(), optionalUsername(c))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Errorf("GetUserByUsername: %v", err)
+			c.AbortWithStatusJSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		if user == nil || user.Role != UserRoleAdmin {
+			c.AbortWithStatusJSON(http.StatusOK, respErrorCode(errorsx.PermissionNotAllowed, c))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// MissionRequest 创建或修改任务(子任务)的参数
+type MissionRequest struct {
+	ID             int64           `json:"id"`
+	Title          string          `json:"title"`
+	TitleCn        string          `json:"title_cn"`
+	Channel        string          `json:"channel"`
+	Logo           string          `json:"logo"`
+	Credit         int64           `json:"credit"`
+	Status         string          `json:"status"`
+	OpenUrl        string          `json:"open_url"`
+	TargetID       string          `json:"target_id"`
+	Verifier       string          `json:"verifier"`
+	Params         json.RawMessage `json:"params"`
+	StartTime      time.Time       `json:"start_time"`
+	EndTime        time.Time       `json:"end_time"`
+	Type           int32           `json:"type"`
+	Recurrence     string          `json:"recurrence"`
+	SortID         int32           `json:"sort_id"`
+	ParentID       int64           `json:"parent_id"`
+	MaxCompletions int64           `json:"max_completions"`
+	CreditBudget   int64           `json:"credit_budget"`
+	SeasonID       int64           `json:"season_id"`
+}
+
+func (r *MissionRequest) toMission(m *model.Mission) {
+	m.Title = strings.TrimSpace(r.Title)
+	m.TitleCn = strings.TrimSpace(r.TitleCn)
+	m.Channel = strings.TrimSpace(r.Channel)
+	m.Logo = r.Logo
+	m.Credit = r.Credit
+	m.OpenUrl = r.OpenUrl
+	m.TargetID = r.TargetID
+	m.Verifier = r.Verifier
+	m.Params = r.Params
+	m.StartTime = r.StartTime
+	m.EndTime = r.EndTime
+	m.Type = r.Type
+	m.Recurrence = r.Recurrence
+	m.SortID = r.SortID
+	m.ParentID = r.ParentID
+	m.MaxCompletions = r.MaxCompletions
+	m.CreditBudget = r.CreditBudget
+	m.SeasonID = r.SeasonID
+
+	if len(m.Params) == 0 || string(m.Params) == "null" {
+		m.Params = nil
+	}
+}
+
+// validateMissionConfig 校验任务配置, 子任务必须属于已存在的任务
+func validateMissionConfig(ctx context.Context, table string, m *model.Mission) error {
+	if m.Title == "" || m.TitleCn == "" {
+		return errors.New("title and title_cn are required")
+	}
+
+	if m.Channel == "" {
+		return errors.New("channel is required")
+	}
+
+	if m.Credit < 0 || m.MaxCompletions < 0 || m.CreditBudget < 0 {
+		return errors.New("credit, max_completions and credit_budget must not be negative")
+	}
+
+	switch m.Type {
+	case MissionTypeBasic, MissionTypeDaily, MissionTypeWeekly:
+	default:
+		return fmt.Errorf("unknown mission type: %d", m.Type)
+	}
+
+	if !m.StartTime.IsZero() && !m.EndTime.IsZero() && m.EndTime.Before(m.StartTime) {
+		return errors.New("end_time must not be before start_time")
+	}
+
+	if m.SeasonID > 0 {
+		if _, err := dao.GetSeasonById(ctx, m.SeasonID); err != nil {
+			return fmt.Errorf("season %d: %w", m.SeasonID, err)
+		}
+	}
+
+	if table == dao.MissionTable {
+		if m.ParentID != 0 {
+			return errors.New("parent_id is only allowed for sub missions")
+		}
+		return ValidateMission(m)
+	}
+
+	if m.ParentID <= 0 {
+		return errors.New("parent_id is required")
+	}
+
+	if _, err := dao.GetMissionFrom(ctx, dao.MissionTable, m.ParentID); err != nil {
+		return fmt.Errorf("parent mission %d: %w", m.ParentID, err)
+	}
+
+	// 子任务由父任务的校验器统一校验, 配置了校验器时才需要校验
+	if m.Verifier != "" {
+		return ValidateMission(m)
+	}
+
+	if _, err := missionRecurrence(m); err != nil {
+		return err
+	}
+
+	params, err := m.GetParams()
+	if err != nil {
+		return fmt.Errorf("parse params: %w", err)
+	}
+
+	return params.Validate()
+}
+
+// recordOperation 记录管理员的操作日志
+func recordOperation(c *gin.Context, title string, businessType int32, param interface{}, result interface{}, opErr error) {
+	paramData, _ := json.Marshal(param)
+	resultData, _ := json.Marshal(result)
+
+	ol := &model.OperationLog{
+		Title:            title,
+		BusinessType:     businessType,
+		Method:           c.HandlerName(),
+		RequestMethod:    c.Request.Method,
+		OperatorType:     UserRoleAdmin,
+		OperatorUsername: optionalUsername(c),
+		OperatorUrl:      c.Request.URL.String(),
+		OperatorIp:       c.ClientIP(),
+		OperatorParam:    string(paramData),
+		JsonResult:       string(resultData),
+		Status:           operationStatusSuccess,
+	}
+
+	if opErr != nil {
+		ol.Status = operationStatusFailure
+		ol.ErrorMsg = opErr.Error()
+	}
+
+	if err := dao.AddOperationLog(c.Request.Context(), ol); err != nil {
+		log.Errorf("AddOperationLog: %v", err)
+	}
+}
+
+func missionTableTitle(table string) string {
+	if table == dao.SubMissionTable {
+		return "sub mission"
+	}
+	return "mission"
+}
+
+// AdminListMissionsHandler 获取所有状态的任务(子任务)
+func AdminListMissionsHandler(table string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parentId, _ := strconv.ParseInt(c.Query("parent_id"), 10, 64)
+
+		missions, err := dao.ListAllMissions(c.Request.Context(), table, parentId)
+		if err != nil {
+			log.Errorf("ListAllMissions: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		c.JSON(http.StatusOK, respJSON(JsonObject{
+			"list": missions,
+		}))
+	}
+}
+
+// AdminCreateMissionHandler 创建任务(子任务), 默认为草稿状态
+func AdminCreateMissionHandler(table string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MissionRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+			return
+		}
+
+		mission := &model.Mission{Status: MissionStatusDraft}
+		req.toMission(mission)
+
+		if req.Status != "" {
+			status, ok := parseMissionStatus(req.Status)
+			if !ok || (status != MissionStatusDraft && status != MissionStatusScheduled && status != MissionStatusActive) {
+				c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+				return
+			}
+			mission.Status = status
+		}
+
+		if err := validateMissionConfig(c.Request.Context(), table, mission); err != nil {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+			return
+		}
+
+		err := dao.CreateMissionIn(c.Request.Context(), table, mission)
+		recordOperation(c, "create "+missionTableTitle(table), BusinessTypeCreate, req, mission, err)
+		if err != nil {
+			log.Errorf("CreateMissionIn: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		c.JSON(http.StatusOK, respJSON(mission))
+	}
+}
+
+// AdminUpdateMissionHandler 修改任务(子任务)配置, 状态通过 AdminMissionStatusHandler 修改
+func AdminUpdateMissionHandler(table string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MissionRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+			return
+		}
+
+		mission, err := dao.GetMissionFrom(c.Request.Context(), table, req.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+			return
+		}
+
+		if err != nil {
+			log.Errorf("GetMissionFrom: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		if mission.Status == MissionStatusArchived {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, errors.New("archived mission cannot be modified"), c))
+			return
+		}
+
+		before := *mission
+		req.toMission(mission)
+
+		if err := validateMissionConfig(c.Request.Context(), table, mission); err != nil {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+			return
+		}
+
+		err = dao.UpdateMissionIn(c.Request.Context(), table, mission)
+		recordOperation(c, "update "+missionTableTitle(table), BusinessTypeUpdate, req, JsonObject{"before": before, "after": mission}, err)
+		if err != nil {
+			log.Errorf("UpdateMissionIn: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		c.JSON(http.StatusOK, respJSON(mission))
+	}
+}
+
+// AdminSortMissionsHandler 批量修改任务(子任务)的排序
+func AdminSortMissionsHandler(table string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			List []struct {
+				ID     int64 `json:"id"`
+				SortID int32 `json:"sort_id"`
+			} `json:"list"`
+		}
+
+		if err := c.BindJSON(&req); err != nil || len(req.List) == 0 {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+			return
+		}
+
+		sortIds := make(map[int64]int32, len(req.List))
+		for _, item := range req.List {
+			sortIds[item.ID] = item.SortID
+		}
+
+		err := dao.UpdateMissionSortIds(c.Request.Context(), table, sortIds)
+		recordOperation(c, "sort "+missionTableTitle(table), BusinessTypeSort, req, nil, err)
+		if errors.Is(err, dao.ErrNoRow) {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.NotFound, err, c))
+			return
+		}
+
+		if err != nil {
+			log.Errorf("UpdateMissionSortIds: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		c.JSON(http.StatusOK, respJSON(nil))
+	}
+}
+
+// AdminMissionStatusHandler 修改任务(子任务)状态, 用于上线、暂停、结束及归档
+func AdminMissionStatusHandler(table string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID     int64  `json:"id"`
+			Status string `json:"status"`
+		}
+
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+			return
+		}
+
+		to, ok := parseMissionStatus(req.Status)
+		if !ok {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+			return
+		}
+
+		mission, err := dao.GetMissionFrom(c.Request.Context(), table, req.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+			return
+		}
+
+		if err != nil {
+			log.Errorf("GetMissionFrom: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		from := mission.Status
+		err = transitMissionStatusIn(c.Request.Context(), table, mission, to)
+		recordOperation(c, "change "+missionTableTitle(table)+" status", BusinessTypeStatus, req,
+			JsonObject{"from": missionStatusName(from), "to": missionStatusName(to)}, err)
+		if err != nil {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+			return
+		}
+
+		c.JSON(http.StatusOK, respJSON(mission))
+	}
+}
+
+// AdminOperationLogsHandler 获取管理员操作日志
+func AdminOperationLogsHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	size, _ := strconv.Atoi(c.Query("size"))
+
+	out, total, err := dao.ListOperationLog(c.Request.Context(), dao.QueryOption{Page: page, PageSize: size})
+	if err != nil {
+		log.Errorf("ListOperationLog: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"total": total,
+		"list":  out,
+	}))
+}
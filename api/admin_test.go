@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMissionConfig(t *testing.T) {
+	ctx := context.Background()
+
+	newMission := func() *model.Mission {
+		return &model.Mission{
+			Title:    "Follow Titan on Twitter",
+			TitleCn:  "关注 Titan 推特",
+			Channel:  "Twitter",
+			Credit:   5,
+			Type:     MissionTypeBasic,
+			Verifier: VerifierFollowTwitter,
+		}
+	}
+
+	require.NoError(t, validateMissionConfig(ctx, dao.MissionTable, newMission()))
+
+	m := newMission()
+	m.TitleCn = ""
+	require.Error(t, validateMissionConfig(ctx, dao.MissionTable, m))
+
+	m = newMission()
+	m.Credit = -1
+	require.Error(t, validateMissionConfig(ctx, dao.MissionTable, m))
+
+	m = newMission()
+	m.Type = 9
+	require.Error(t, validateMissionConfig(ctx, dao.MissionTable, m))
+
+	m = newMission()
+	m.Verifier = "unknown"
+	require.Error(t, validateMissionConfig(ctx, dao.MissionTable, m))
+
+	m = newMission()
+	m.ParentID = 1000
+	require.Error(t, validateMissionConfig(ctx, dao.MissionTable, m))
+
+	// 子任务必须指定父任务
+	require.Error(t, validateMissionConfig(ctx, dao.SubMissionTable, newMission()))
+}
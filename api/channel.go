@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// RespChannel 渠道及其下的任务
+type RespChannel struct {
+	Code     string        `json:"code"`
+	Group    string        `json:"group"`
+	Title    string        `json:"title"`
+	Icon     string        `json:"icon"`
+	SortID   int32         `json:"sort_id"`
+	Missions []interface{} `json:"missions"`
+}
+
+// channelGrouper 按渠道注册表对任务分组, 未注册的渠道排在最后, 不会丢弃
+type channelGrouper struct {
+	channels []*RespChannel
+	byCode   map[string]*RespChannel
+	groups   []string
+}
+
+func newChannelGrouper(channels []*model.Channel, locale string) *channelGrouper {
+	g := &channelGrouper{byCode: make(map[string]*RespChannel)}
+	for _, ch := range channels {
+		g.add(&RespChannel{
+			Code:   ch.Code,
+			Group:  channelGroup(ch),
+			Title:  channelTitle(ch, locale),
+			Icon:   ch.Icon,
+			SortID: ch.SortID,
+		})
+	}
+	return g
+}
+
+func (g *channelGrouper) add(ch *RespChannel) {
+	key := strings.ToLower(ch.Code)
+	if _, ok := g.byCode[key]; ok {
+		return
+	}
+
+	g.channels = append(g.channels, ch)
+	g.byCode[key] = ch
+
+	for _, group := range g.groups {
+		if group == ch.Group {
+			return
+		}
+	}
+	g.groups = append(g.groups, ch.Group)
+}
+
+// Add 将任务加入所属渠道
+func (g *channelGrouper) Add(channel string, mission interface{}) {
+	ch, ok := g.byCode[strings.ToLower(channel)]
+	if !ok {
+		ch = &RespChannel{Code: channel, Group: channelGroup(&model.Channel{Code: channel}), Title: channel}
+		g.add(ch)
+	}
+	ch.Missions = append(ch.Missions, mission)
+}
+
+// Channels 获取有任务的渠道
+func (g *channelGrouper) Channels() []*RespChannel {
+	out := make([]*RespChannel, 0, len(g.channels))
+	for _, ch := range g.channels {
+		if len(ch.Missions) > 0 {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// Groups 按分组返回任务, 键为 <group>_missions, 兼容旧版本的 basic_missions、twitter_missions 等
+func (g *channelGrouper) Groups() JsonObject {
+	out := make(JsonObject, len(g.groups))
+	for _, group := range g.groups {
+		var missions []interface{}
+		for _, ch := range g.channels {
+			if ch.Group == group {
+				missions = append(missions, ch.Missions...)
+			}
+		}
+		out[group+"_missions"] = missions
+	}
+	return out
+}
+
+// channelGroup 渠道分组, 未设置时使用小写的 code
+func channelGroup(ch *model.Channel) string {
+	if ch.Group != "" {
+		return ch.Group
+	}
+	return strings.ToLower(ch.Code)
+}
+
+// channelTitle 获取渠道名称, 依次使用对应语言的文案、中文名称及英文名称
+func channelTitle(ch *model.Channel, locale string) string {
+	if title := localize(fmt.Sprintf("channel.%s.title", ch.Code), locale, ch.Title, ch.TitleCn); title != "" {
+		return title
+	}
+	return ch.Code
+}
+
+// AdminChannelsHandler 获取所有渠道
+func AdminChannelsHandler(c *gin.Context) {
+	out, err := dao.GetChannels(c.Request.Context())
+	if err != nil {
+		log.Errorf("GetChannels: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(out))
+}
+
+// AdminSaveChannelHandler 按 code 新增或修改渠道, 任务的 channel 字段使用渠道的 code
+func AdminSaveChannelHandler(c *gin.Context) {
+	var req struct {
+		Code    string `json:"code"`
+		Group   string `json:"group"`
+		Title   string `json:"title"`
+		TitleCn string `json:"title_cn"`
+		Icon    string `json:"icon"`
+		SortID  int32  `json:"sort_id"`
+	}
+
+	if err := c.BindJSON(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	ch := &model.Channel{
+		Code:    strings.TrimSpace(req.Code),
+		Group:   strings.ToLower(strings.TrimSpace(req.Group)),
+		Title:   strings.TrimSpace(req.Title),
+		TitleCn: strings.TrimSpace(req.TitleCn),
+		Icon:    strings.TrimSpace(req.Icon),
+		SortID:  req.SortID,
+	}
+
+	err := dao.SaveChannel(c.Request.Context(), ch)
+	recordOperation(c, "save channel", BusinessTypeUpdate, req, nil, err)
+	if err != nil {
+		log.Errorf("SaveChannel: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(nil))
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelGrouper(t *testing.T) {
+	channels := []*model.Channel{
+		{Code: "Wallet", Group: "basic", Title: "Wallet", TitleCn: "钱包", SortID: 1},
+		{Code: "Titan", Group: "basic", Title: "Titan", SortID: 2},
+		{Code: "Twitter", Group: "twitter", Title: "Twitter", TitleCn: "推特", SortID: 3},
+	}
+
+	g := newChannelGrouper(channels, model.LanguageCN)
+	g.Add("Titan", 1)
+	g.Add("twitter", 2)
+	g.Add("Wallet", 3)
+	// unregistered channels are kept at the end
+	g.Add("GitHub", 4)
+
+	out := g.Channels()
+	require.Len(t, out, 4)
+	require.Equal(t, "Wallet", out[0].Code)
+	require.Equal(t, "钱包", out[0].Title)
+	require.Equal(t, "Titan", out[1].Title)
+	require.Equal(t, "推特", out[2].Title)
+	require.Equal(t, "GitHub", out[3].Code)
+	require.Equal(t, "github", out[3].Group)
+
+	groups := g.Groups()
+	require.Equal(t, []interface{}{3, 1}, groups["basic_missions"])
+	require.Equal(t, []interface{}{2}, groups["twitter_missions"])
+	require.Equal(t, []interface{}{4}, groups["github_missions"])
+}
@@ -28,3 +28,50 @@ const (
 	MissionTypeDaily
 	MissionTypeWeekly
 )
+
+// 任务状态, 对应 mission.status 字段
+const (
+	MissionStatusDraft int32 = iota
+	MissionStatusActive
+	MissionStatusScheduled
+	MissionStatusPaused
+	MissionStatusEnded
+	MissionStatusArchived
+)
+
+// 任务校验作业状态
+const (
+	VerifyJobStatusPending   = "pending"
+	VerifyJobStatusRunning   = "running"
+	VerifyJobStatusRetrying  = "retrying"
+	VerifyJobStatusSucceeded = "succeeded"
+	VerifyJobStatusFailed    = "failed"
+)
+
+// 用户角色, 对应 users.role 字段
+const (
+	UserRoleUser int32 = iota
+	UserRoleAdmin
+)
+
+// 操作日志的业务类型, 对应 operation_log.business_type 字段
+const (
+	BusinessTypeOther int32 = iota
+	BusinessTypeCreate
+	BusinessTypeUpdate
+	BusinessTypeSort
+	BusinessTypeStatus
+	BusinessTypeDelete
+)
+
+// 操作日志的操作结果, 对应 operation_log.status 字段
+const (
+	operationStatusFailure int32 = iota
+	operationStatusSuccess
+)
+
+const (
+	defaultBrowseHeartbeatInterval = 10 // 秒
+	// browseSessionIdleHeartbeats 连续多少个心跳间隔没有心跳时浏览会话过期
+	browseSessionIdleHeartbeats = 6
+)
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+)
+
+// AdminAdjustCreditsHandler 管理员调整用户积分, amount 为负数时扣减, 同一 ref_id 只能提交一次
+func AdminAdjustCreditsHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	operator := claims[identityKey].(string)
+
+	var req struct {
+		Username string `json:"username"`
+		Amount   int64  `json:"amount"`
+		RefID    string `json:"ref_id"`
+		Reason   string `json:"reason"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	req.RefID = strings.TrimSpace(req.RefID)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Username == "" || req.Amount == 0 || req.RefID == "" || req.Reason == "" {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, errors.New("username, amount, ref_id and reason are required"), c))
+		return
+	}
+
+	_, err := dao.GetUserByUsername(c.Request.Context(), req.Username)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.UserNotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("GetUserByUsername: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	entry, err := dao.AdjustUserCredits(c.Request.Context(), req.Username, req.Amount, req.RefID, req.Reason, operator)
+	recordOperation(c, "adjust credits", BusinessTypeCreate, req, entry, err)
+	if errors.Is(err, dao.ErrCreditAdjustmentExists) {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("AdjustUserCredits: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(entry))
+}
+
+// AdminRevokeCreditHandler 管理员撤销一条积分流水, 追加一条金额相反的撤销流水
+func AdminRevokeCreditHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	operator := claims[identityKey].(string)
+
+	var req struct {
+		ID     int64  `json:"id"`
+		Reason string `json:"reason"`
+	}
+
+	if err := c.BindJSON(&req); err != nil || req.ID <= 0 {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, errors.New("reason is required"), c))
+		return
+	}
+
+	entry, err := dao.RevokeCreditEntry(c.Request.Context(), req.ID, req.Reason, operator)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	recordOperation(c, "revoke credit entry", BusinessTypeStatus, req, entry, err)
+	if errors.Is(err, dao.ErrCreditEntryRevoked) || errors.Is(err, dao.ErrCreditEntryNotRevocable) {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("RevokeCreditEntry: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(entry))
+}
@@ -1,32 +1,19 @@
 package api
 
 import (
-	_ "embed"
 	"fmt"
 	"github.com/gnasnik/titan-quest/config"
-	"github.com/gnasnik/titan-quest/core/generated/model"
 	"github.com/gnasnik/titan-quest/pkg/mail"
 	"github.com/pkg/errors"
 	"math/rand"
 	"strconv"
 )
 
-//go:embed template/en/mail.html
-var contentEn string
-
-//go:embed template/cn/mail.html
-var contentCn string
-
-func sendEmail(sendTo string, vc, lang string) error {
-	emailSubject := map[string]string{
-		"":               "[Titan Network] Your verification code",
-		model.LanguageEN: "[Titan Network] Your verification code",
-		model.LanguageCN: "[Titan Network] 您的验证码",
-	}
-
-	content := contentEn
-	if lang == model.LanguageCN {
-		content = contentCn
+// sendEmail 发送对应语言的验证码邮件
+func sendEmail(sendTo string, vc, locale string) error {
+	content, err := mailTemplate("mail.html", locale)
+	if err != nil {
+		return err
 	}
 
 	var verificationBtn = ""
@@ -50,7 +37,7 @@ func sendEmail(sendTo string, vc, lang string) error {
 		log.Errorf("parse port: %v", err)
 	}
 
-	message := mail.NewEmailMessage(mailCfg.From, mailCfg.Nickname, emailSubject[lang], contentType, content, "", []string{sendTo}, nil)
+	message := mail.NewEmailMessage(mailCfg.From, mailCfg.Nickname, catalog.Text(locale, msgKeyEmailVerifyCodeSubject), contentType, content, "", []string{sendTo}, nil)
 	client := mail.NewEmailClient(mailCfg.SMTPHost, mailCfg.Username, mailCfg.Password, int(port), message)
 	_, err = client.SendMessage()
 	if err != nil {
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/gnasnik/titan-quest/pkg/github"
+	"github.com/gnasnik/titan-quest/pkg/random"
+	"golang.org/x/oauth2"
+)
+
+var (
+	githubClient   github.Client
+	githubClientMu sync.Mutex
+)
+
+// getGithubClient 获取 GitHub 客户端, 未设置时使用配置的接口地址创建
+func getGithubClient() github.Client {
+	githubClientMu.Lock()
+	defer githubClientMu.Unlock()
+
+	if githubClient == nil {
+		githubClient = github.NewClient(config.Cfg.GithubAPIURL, nil)
+	}
+	return githubClient
+}
+
+// setGithubClient 替换 GitHub 客户端
+func setGithubClient(client github.Client) {
+	githubClientMu.Lock()
+	defer githubClientMu.Unlock()
+
+	githubClient = client
+}
+
+func githubOAuthConfig(redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.Cfg.GithubClientId,
+		ClientSecret: config.Cfg.GithubClientSecret,
+		RedirectURL:  redirectURI,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://github.com/login/oauth/authorize",
+			TokenURL: "https://github.com/login/oauth/access_token",
+		},
+		Scopes: []string{"read:user"},
+	}
+}
+
+// githubError 包装 GitHub 接口返回的错误, 限流、服务端错误及网络错误视为临时错误
+func githubError(err error) error {
+	var ge *github.Error
+	if errors.As(err, &ge) && !ge.Temporary() {
+		return err
+	}
+	return transient(err)
+}
+
+// GithubOAuthHandler 获取 GitHub 授权地址
+func GithubOAuthHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	redirectURI := c.Query("redirect_uri")
+	if redirectURI == "" {
+		redirectURI = config.Cfg.RedirectURI
+	}
+
+	state := random.GenerateRandomString(12)
+	err := dao.AddGithubOAuth(c.Request.Context(), &model.GithubOauth{Username: username, State: state, RedirectUri: redirectURI})
+	if err != nil {
+		log.Errorf("AddGithubOAuth: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"url": githubOAuthConfig(redirectURI).AuthCodeURL(state),
+	}))
+}
+
+// GithubCallBackHandler GitHub 授权回调, 绑定 GitHub 账号
+func GithubCallBackHandler(c *gin.Context) {
+	code := c.Query("code")
+	state := c.Query("state")
+
+	if code == "" || state == "" {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	ga, err := dao.GetGithubOAuthByState(c.Request.Context(), state)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("GetGithubOAuthByState: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	if ga.RedirectUri == "" {
+		ga.RedirectUri = config.Cfg.RedirectURI
+	}
+
+	tokens, err := githubOAuthConfig(ga.RedirectUri).Exchange(c.Request.Context(), code)
+	if err != nil {
+		log.Errorf("Exchange: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	u, err := getGithubClient().User(c.Request.Context(), tokens.AccessToken)
+	if err != nil {
+		log.Errorf("Get User: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	githubUserId := strconv.FormatInt(u.ID, 10)
+	existing, err := dao.GetGithubOAuth(c.Request.Context(), githubUserId)
+	if existing != nil && existing.Username != ga.Username {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.SocialMediaAccountIsAlreadyInUse, c))
+		return
+	}
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Errorf("GetGithubOAuth: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	err = dao.UpdateGithubUserInfo(c.Request.Context(), state, githubUserId, u.Login, tokens.AccessToken)
+	if err != nil {
+		log.Errorf("UpdateGithubUserInfo: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(nil))
+}
+
+// githubRepository 解析任务配置的仓库
+func githubRepository(params *model.MissionParams) (string, string) {
+	owner, repo, _ := strings.Cut(params.Repository, "/")
+	return owner, repo
+}
+
+// checkGithub 校验用户绑定的 GitHub 账号是否满足 check, 满足时发放积分
+func checkGithub(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption,
+	check func(ctx context.Context, client github.Client, account *model.GithubOauth, params *model.MissionParams) (bool, error)) error {
+	account, err := dao.GetGithubOAuthByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("github account not bound")
+	}
+
+	if err != nil {
+		return transient(err)
+	}
+
+	params, err := mission.GetParams()
+	if err != nil {
+		return err
+	}
+
+	ok, err := check(ctx, getGithubClient(), account, params)
+	if err != nil {
+		return githubError(err)
+	}
+
+	if !ok {
+		return fmt.Errorf("github user %s has not completed the mission", account.GithubLogin)
+	}
+
+	return dao.AddUserMissionAndInviteLog(ctx, &model.UserMission{
+		Username:  username,
+		MissionID: mission.ID,
+		Type:      mission.Type,
+		Credit:    mission.Credit,
+		Content:   account.GithubLogin,
+		Period:    awardPeriod(queryOpt),
+		CreatedAt: time.Now(),
+	})
+}
+
+func checkStarGithubRepo(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	return checkGithub(ctx, mission, username, queryOpt, func(ctx context.Context, client github.Client, account *model.GithubOauth, params *model.MissionParams) (bool, error) {
+		owner, repo := githubRepository(params)
+		return client.IsStarred(ctx, account.AccessToken, owner, repo)
+	})
+}
+
+func checkForkGithubRepo(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	return checkGithub(ctx, mission, username, queryOpt, func(ctx context.Context, client github.Client, account *model.GithubOauth, params *model.MissionParams) (bool, error) {
+		owner, repo := githubRepository(params)
+		return client.HasForked(ctx, account.AccessToken, account.GithubLogin, owner, repo)
+	})
+}
+
+func checkGithubMergedPR(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	return checkGithub(ctx, mission, username, queryOpt, func(ctx context.Context, client github.Client, account *model.GithubOauth, params *model.MissionParams) (bool, error) {
+		return client.HasMergedPullRequest(ctx, account.AccessToken, account.GithubLogin, params.Organization)
+	})
+}
+
+func requireGithubRepository(mission *model.Mission, params *model.MissionParams) error {
+	if params.Repository == "" {
+		return errors.New("missing repository")
+	}
+	return nil
+}
+
+func requireGithubOrganization(mission *model.Mission, params *model.MissionParams) error {
+	if params.Organization == "" {
+		return errors.New("missing organization")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/gnasnik/titan-quest/pkg/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGithubError(t *testing.T) {
+	require.False(t, isTransientError(githubError(&github.Error{StatusCode: http.StatusUnauthorized})))
+	require.True(t, isTransientError(githubError(&github.Error{StatusCode: http.StatusForbidden, RateLimited: true})))
+	require.True(t, isTransientError(githubError(&github.Error{StatusCode: http.StatusBadGateway})))
+	require.True(t, isTransientError(githubError(errors.New("connection reset"))))
+}
+
+func TestGithubMissionParams(t *testing.T) {
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierStarGithubRepo, Type: MissionTypeBasic}))
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierStarGithubRepo, Type: MissionTypeBasic, Params: []byte(`{"repository":"titan-node"}`)}))
+	require.NoError(t, ValidateMission(&model.Mission{Verifier: VerifierForkGithubRepo, Type: MissionTypeBasic, Params: []byte(`{"repository":"Titannet-dao/titan-node"}`)}))
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierGithubMergedPR, Type: MissionTypeBasic}))
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierGithubMergedPR, Type: MissionTypeBasic, Params: []byte(`{"organization":"Titannet-dao author:octocat"}`)}))
+	require.NoError(t, ValidateMission(&model.Mission{Verifier: VerifierGithubMergedPR, Type: MissionTypeBasic, Params: []byte(`{"organization":"Titannet-dao"}`)}))
+
+	owner, repo := githubRepository(&model.MissionParams{Repository: "Titannet-dao/titan-node"})
+	require.Equal(t, "Titannet-dao", owner)
+	require.Equal(t, "titan-node", repo)
+}
+
+func TestGithubClientReplaceable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	setGithubClient(github.NewClient(server.URL, server.Client()))
+	defer setGithubClient(nil)
+
+	starred, err := getGithubClient().IsStarred(context.Background(), "token", "Titannet-dao", "titan-node")
+	require.NoError(t, err)
+	require.True(t, starred)
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	swagger "github.com/gnasnik/titan-quest/go-client-generated"
+	"github.com/valyala/fastjson"
+)
+
+const (
+	defaultHashtagSearchInterval = 10 // 分钟
+	defaultHashtagSearchMaxPages = 5
+)
+
+func hashtagSearchConfig() config.HashtagSearchConfig {
+	cfg := config.Cfg.HashtagSearch
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultHashtagSearchInterval
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = defaultHashtagSearchMaxPages
+	}
+	return cfg
+}
+
+// hashtagSearchWords 任务话题标签的搜索词, 推文需要包含全部话题标签
+func hashtagSearchWords(params *model.MissionParams) string {
+	words := make([]string, 0, len(params.RequiredHashtags))
+	for _, tag := range params.RequiredHashtags {
+		words = append(words, "#"+strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	}
+	return strings.Join(words, " ")
+}
+
+// searchHashtagTweets 按游标翻页搜索 since 之后发布且满足任务内容要求的推文, from 不为空时只搜索该账号的推文.
+// fn 返回 false 时停止搜索
+func searchHashtagTweets(ctx context.Context, params *model.MissionParams, from string, since time.Time, maxPages int, fn func(tweet *fastjson.Value) bool) error {
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+
+	var cursor string
+	for page := 0; page < maxPages; page++ {
+		option := &swagger.TwitterSearchApiToolsApiSearchUsingGETOpts{}
+		if from != "" {
+			option.From = optional.NewString("@" + from)
+		}
+		if !since.IsZero() {
+			option.Since = optional.NewString(since.UTC().Format("2006-01-02"))
+		}
+		if cursor != "" {
+			option.Cursor = optional.NewString(cursor)
+		}
+
+		result, httpResp, err := client.TwitterSearchApiToolsApi.SearchUsingGET(ctx, GetUToolKeyByRoundRobin(), hashtagSearchWords(params), option)
+		if err != nil {
+			log.Errorf("SearchUsingGET: %v", err)
+			return upstreamError(err, httpResp)
+		}
+
+		v, err := utoolData(result)
+		if err != nil {
+			return err
+		}
+
+		tweets, next := timelineTweets(v)
+
+		expired := len(tweets) > 0
+		for _, tweet := range tweets {
+			if !since.IsZero() && tweetCreatedAt(tweet).Before(since) {
+				continue
+			}
+			expired = false
+
+			if checkTweetContent(tweet, params) != nil {
+				continue
+			}
+
+			if !fn(tweet) {
+				return nil
+			}
+		}
+
+		// 搜索结果按时间倒序返回, 整页都早于当前周期时不再翻页
+		if next == "" || next == cursor || expired {
+			break
+		}
+		cursor = next
+	}
+
+	return nil
+}
+
+// hashtagPeriodStart 当前周期的开始时间, 不重复的任务从任务开始时间算起
+func hashtagPeriodStart(mission *model.Mission, queryOpt dao.QueryOption) time.Time {
+	if since := periodStart(queryOpt); !since.IsZero() {
+		return since
+	}
+	return mission.StartTime
+}
+
+// checkHashtagTweet 校验用户在当前周期内是否发布了带有任务话题标签的推文, 定时搜索未覆盖到的用户可以手动校验
+func checkHashtagTweet(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	twitterUser, err := dao.GetTwitterOauthByUsername(ctx, username)
+	if err != nil {
+		log.Errorf("GetTwitterOauthByUsername: %v", err)
+		return err
+	}
+
+	params, err := mission.GetParams()
+	if err != nil {
+		log.Errorf("GetParams: %v", err)
+		return err
+	}
+
+	var found *fastjson.Value
+	err = searchHashtagTweets(ctx, params, twitterUser.TwitterScreenName, hashtagPeriodStart(mission, queryOpt), hashtagSearchConfig().MaxPages, func(tweet *fastjson.Value) bool {
+		if tweetAuthorId(tweet) == twitterUser.TwitterUserID {
+			found = tweet
+		}
+		return found == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if found == nil {
+		return errors.New("hashtag tweet not found")
+	}
+
+	return awardHashtagTweet(ctx, mission, username, queryOpt, found)
+}
+
+func awardHashtagTweet(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption, tweet *fastjson.Value) error {
+	return dao.AddUserMissionAndInviteLog(ctx, &model.UserMission{
+		Username:  username,
+		MissionID: mission.ID,
+		Type:      mission.Type,
+		Credit:    mission.Credit,
+		Content:   tweetId(tweet),
+		Period:    awardPeriod(queryOpt),
+		CreatedAt: time.Now(),
+	})
+}
+
+func requireHashtags(mission *model.Mission, params *model.MissionParams) error {
+	if len(params.RequiredHashtags) == 0 {
+		return errors.New("missing required_hashtags")
+	}
+	return nil
+}
+
+// StartHashtagSearchJob 启动话题标签任务的定时搜索, 自动为发布了话题推文的用户发放积分
+func StartHashtagSearchJob(ctx context.Context, cfg *config.Config) {
+	if !cfg.HashtagSearch.Enable {
+		return
+	}
+
+	go func() {
+		interval := time.Duration(hashtagSearchConfig().Interval) * time.Minute
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				searchHashtagMissions(ctx, now)
+			}
+		}
+	}()
+}
+
+// searchHashtagMissions 搜索一轮进行中的话题标签任务
+func searchHashtagMissions(ctx context.Context, now time.Time) {
+	missions, err := getMissionsByStatus(ctx, MissionStatusActive)
+	if err != nil {
+		log.Errorf("getMissionsByStatus: %v", err)
+		return
+	}
+
+	for _, mission := range missions {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if mission.Verifier != VerifierHashtagTweet {
+			continue
+		}
+
+		if err := searchHashtagMission(ctx, mission, now); err != nil {
+			log.Errorf("search hashtag mission %d: %v", mission.ID, err)
+		}
+	}
+}
+
+// searchHashtagMission 搜索任务当前周期内的话题推文, 推文作者绑定了推特账号时发放积分, 每个用户每个周期只发放一次
+func searchHashtagMission(ctx context.Context, mission *model.Mission, now time.Time) error {
+	if missionState(mission, now) != MissionStatusActive {
+		return nil
+	}
+
+	params, err := mission.GetParams()
+	if err != nil {
+		return err
+	}
+
+	window, err := missionWindow(mission, now)
+	if err != nil {
+		return err
+	}
+	windowOpt := windowQueryOption(window)
+
+	// 同一轮中每个推特账号只处理一次
+	seen := make(map[string]bool)
+
+	return searchHashtagTweets(ctx, params, "", hashtagPeriodStart(mission, windowOpt), hashtagSearchConfig().MaxPages, func(tweet *fastjson.Value) bool {
+		authorId := tweetAuthorId(tweet)
+		if authorId == "" || seen[authorId] {
+			return true
+		}
+		seen[authorId] = true
+
+		twitterUser, err := dao.GetTwitterOauth(ctx, authorId)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Errorf("GetTwitterOauth: %v", err)
+			}
+			return true
+		}
+
+		ums, err := dao.GetUserMissionByMissionId(ctx, twitterUser.Username, mission.ID, windowOpt)
+		if err != nil {
+			log.Errorf("GetUserMissionByMissionId: %v", err)
+			return true
+		}
+
+		if len(ums) > 0 {
+			return true
+		}
+
+		queryOpt, code := checkUserMissionSubmittable(ctx, mission, twitterUser.Username, now)
+		if code == errorsx.MissionSoldOut {
+			return false
+		}
+
+		if code != 0 {
+			return true
+		}
+
+		err = awardHashtagTweet(ctx, mission, twitterUser.Username, queryOpt, tweet)
+		if errors.Is(err, dao.ErrMissionSoldOut) {
+			return false
+		}
+
+		if err != nil && !errors.Is(err, dao.ErrAlreadyAwarded) {
+			log.Errorf("awardHashtagTweet: %v", err)
+		}
+		return true
+	})
+}
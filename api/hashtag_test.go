@@ -0,0 +1,25 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashtagMissionParams(t *testing.T) {
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierHashtagTweet, Type: MissionTypeDaily, Recurrence: "daily"}))
+	require.NoError(t, ValidateMission(&model.Mission{Verifier: VerifierHashtagTweet, Type: MissionTypeDaily, Recurrence: "daily", Params: []byte(`{"required_hashtags":["TitanNetwork","#DePIN"]}`)}))
+
+	require.Equal(t, "#TitanNetwork #DePIN", hashtagSearchWords(&model.MissionParams{RequiredHashtags: []string{"TitanNetwork", " #DePIN"}}))
+}
+
+func TestHashtagPeriodStart(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	mission := &model.Mission{StartTime: start}
+
+	require.Equal(t, start, hashtagPeriodStart(mission, dao.QueryOption{}))
+	require.Equal(t, start.AddDate(0, 0, 3), hashtagPeriodStart(mission, dao.QueryOption{StartTime: "2024-06-04 00:00:00"}))
+}
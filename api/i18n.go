@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/gnasnik/titan-quest/pkg/i18n"
+)
+
+const defaultI18nSyncInterval = 5 // 分钟
+
+// 文案 key, 任务标题使用 mission.<id>.title 及 sub_mission.<id>.title
+const (
+	msgKeyEmailVerifyCodeSubject = "email.verify_code.subject"
+	msgKeyCompleteMissionFirst   = "mission.complete_first"
+)
+
+// localePattern 语言名称, 如 en, ko, pt-br
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+//go:embed template
+var templates embed.FS
+
+// catalog 全局文案, 内置文案之外的语言及覆盖的文案从 translation 表加载
+var catalog = newCatalog(model.LanguageEN)
+
+func newCatalog(fallback string) *i18n.Catalog {
+	c := i18n.NewCatalog(fallback)
+
+	for code, text := range errorsx.ErrMap {
+		messages := strings.SplitN(text, ":", 2)
+		c.Set(model.LanguageEN, errorMessageKey(code), messages[0])
+		if len(messages) > 1 {
+			c.Set(model.LanguageCN, errorMessageKey(code), messages[1])
+		}
+	}
+
+	for key, messages := range builtinMessages {
+		for locale, text := range messages {
+			c.Set(locale, key, text)
+		}
+	}
+
+	return c
+}
+
+var builtinMessages = map[string]map[string]string{
+	msgKeyEmailVerifyCodeSubject: {
+		model.LanguageEN: "[Titan Network] Your verification code",
+		model.LanguageCN: "[Titan Network] 您的验证码",
+	},
+	msgKeyCompleteMissionFirst: {
+		model.LanguageEN: "Please complete the task first",
+		model.LanguageCN: "请先完成任务",
+	},
+	notificationMessageKey(NotificationMissionRecheckFailed): {
+		model.LanguageEN: "We could not verify your completion of \"{title}\" any more. Please complete it again before {deadline}, otherwise the {credit} credits will be revoked.",
+		model.LanguageCN: "任务「{title}」重新校验未通过, 请在 {deadline} 前重新完成, 否则将撤销获得的 {credit} 积分。",
+	},
+	notificationMessageKey(NotificationMissionRevoked): {
+		model.LanguageEN: "The {credit} credits of \"{title}\" have been revoked because the mission is no longer completed.",
+		model.LanguageCN: "任务「{title}」已不满足完成条件, 获得的 {credit} 积分已撤销。",
+	},
+	notificationMessageKey(NotificationSubmissionApproved): {
+		model.LanguageEN: "Your submission for \"{title}\" has been approved, you got {credit} credits.",
+		model.LanguageCN: "任务「{title}」提交的内容已审核通过, 获得 {credit} 积分。",
+	},
+	notificationMessageKey(NotificationSubmissionRejected): {
+		model.LanguageEN: "Your submission for \"{title}\" has been rejected: {reason}",
+		model.LanguageCN: "任务「{title}」提交的内容未通过审核: {reason}",
+	},
+}
+
+func errorMessageKey(code int) string {
+	return fmt.Sprintf("error.%d", code)
+}
+
+func notificationMessageKey(notificationType string) string {
+	return "notification." + notificationType
+}
+
+func missionTitleKey(table string, id int64) string {
+	return fmt.Sprintf("%s.%d.title", table, id)
+}
+
+// InitI18n 加载文案表并定期重新加载
+func InitI18n(ctx context.Context, cfg *config.Config) {
+	if cfg.I18n.DefaultLocale != "" {
+		catalog = newCatalog(cfg.I18n.DefaultLocale)
+	}
+
+	if err := loadTranslations(ctx); err != nil {
+		log.Errorf("loadTranslations: %v", err)
+	}
+
+	interval := cfg.I18n.SyncInterval
+	if interval <= 0 {
+		interval = defaultI18nSyncInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := loadTranslations(ctx); err != nil {
+					log.Errorf("loadTranslations: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func loadTranslations(ctx context.Context) error {
+	translations, err := dao.GetTranslations(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	messages := make(map[string]map[string]string)
+	for _, t := range translations {
+		if messages[t.Locale] == nil {
+			messages[t.Locale] = make(map[string]string)
+		}
+		messages[t.Locale][t.Key] = t.Text
+	}
+
+	catalog.Load(messages)
+	return nil
+}
+
+// requestLocale 获取请求的语言, 优先使用 Lang 请求头, 其次 Accept-Language, 都不支持时使用默认语言
+func requestLocale(c *gin.Context) string {
+	return catalog.Resolve(c.GetHeader("Lang"), c.GetHeader("Accept-Language"))
+}
+
+// localize 获取对应语言的文案, 没有文案时中文使用 cn, 英文使用 en, 都没有时使用 en
+func localize(key, locale, en, cn string) string {
+	for _, l := range i18n.Chain(locale, catalog.Fallback()) {
+		if text, ok := catalog.Get(l, key); ok {
+			return text
+		}
+		if l == model.LanguageCN && cn != "" {
+			return cn
+		}
+		if l == model.LanguageEN && en != "" {
+			return en
+		}
+	}
+	return en
+}
+
+// missionTitle 获取任务标题, 依次使用对应语言的文案、中文标题及英文标题
+func missionTitle(table string, m *model.Mission, locale string) string {
+	return localize(missionTitleKey(table, m.ID), locale, m.Title, m.TitleCn)
+}
+
+// localizeMission 返回标题为对应语言的任务副本
+func localizeMission(table string, m *model.Mission, locale string) *model.Mission {
+	out := *m
+	out.Title = missionTitle(table, m, locale)
+	return &out
+}
+
+// mailTemplate 获取对应语言的邮件模板, 配置的模板目录优先于内置模板
+func mailTemplate(name, locale string) (string, error) {
+	for _, l := range i18n.Chain(locale, catalog.Fallback()) {
+		if dir := config.Cfg.I18n.TemplateDir; dir != "" {
+			content, err := os.ReadFile(filepath.Join(dir, l, name))
+			if err == nil {
+				return string(content), nil
+			}
+		}
+
+		content, err := templates.ReadFile("template/" + l + "/" + name)
+		if err == nil {
+			return string(content), nil
+		}
+	}
+
+	content, err := templates.ReadFile("template/" + model.LanguageEN + "/" + name)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// AdminTranslationsHandler 获取文案表中的文案及当前支持的语言
+func AdminTranslationsHandler(c *gin.Context) {
+	out, err := dao.GetTranslations(c.Request.Context(), i18n.Normalize(c.Query("locale")))
+	if err != nil {
+		log.Errorf("GetTranslations: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"locales": catalog.Locales(),
+		"list":    out,
+	}))
+}
+
+type TranslationRequest struct {
+	Locale string `json:"locale"`
+	Key    string `json:"key"`
+	Text   string `json:"text"`
+}
+
+func (r *TranslationRequest) validate() bool {
+	r.Locale = i18n.Normalize(r.Locale)
+	r.Key = strings.TrimSpace(r.Key)
+	return localePattern.MatchString(r.Locale) && r.Key != ""
+}
+
+// AdminSaveTranslationHandler 新增或修改文案, 新增语言只需要添加该语言的文案
+func AdminSaveTranslationHandler(c *gin.Context) {
+	var req TranslationRequest
+	if err := c.BindJSON(&req); err != nil || !req.validate() || req.Text == "" {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	err := dao.SaveTranslation(c.Request.Context(), &model.Translation{Locale: req.Locale, Key: req.Key, Text: req.Text})
+	recordOperation(c, "save translation", BusinessTypeUpdate, req, nil, err)
+	if err != nil {
+		log.Errorf("SaveTranslation: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	if err := loadTranslations(c.Request.Context()); err != nil {
+		log.Errorf("loadTranslations: %v", err)
+	}
+
+	c.JSON(http.StatusOK, respJSON(nil))
+}
+
+// AdminDeleteTranslationHandler 删除文案, 删除后恢复使用内置文案
+func AdminDeleteTranslationHandler(c *gin.Context) {
+	var req TranslationRequest
+	if err := c.BindJSON(&req); err != nil || !req.validate() {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	err := dao.DeleteTranslation(c.Request.Context(), req.Locale, req.Key)
+	recordOperation(c, "delete translation", BusinessTypeDelete, req, nil, err)
+	if err != nil {
+		log.Errorf("DeleteTranslation: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	if err := loadTranslations(c.Request.Context()); err != nil {
+		log.Errorf("loadTranslations: %v", err)
+	}
+
+	c.JSON(http.StatusOK, respJSON(nil))
+}
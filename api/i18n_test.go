@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestI18nCatalog(t *testing.T) {
+	old := catalog
+	defer func() { catalog = old }()
+
+	catalog = newCatalog(model.LanguageEN)
+	catalog.Load(map[string]map[string]string{
+		"ko": {
+			errorMessageKey(errorsx.InvalidParams):             "잘못된 매개변수",
+			missionTitleKey(dao.MissionTable, 1002):            "팔로우",
+			notificationMessageKey(NotificationMissionRevoked): "\"{title}\"의 {credit} 크레딧이 취소되었습니다.",
+		},
+	})
+
+	require.Equal(t, "invalid params", errorMessage(errorsx.InvalidParams, ""))
+	require.Equal(t, "参数有误", errorMessage(errorsx.InvalidParams, model.LanguageCN))
+	require.Equal(t, "잘못된 매개변수", errorMessage(errorsx.InvalidParams, "ko"))
+	// missing translations fall back to english
+	require.Equal(t, "not found", errorMessage(errorsx.NotFound, "ko"))
+
+	mission := &model.Mission{ID: 1002, Title: "Follow", TitleCn: "关注"}
+	require.Equal(t, "팔로우", missionTitle(dao.MissionTable, mission, "ko"))
+	require.Equal(t, "关注", missionTitle(dao.MissionTable, mission, model.LanguageCN))
+	require.Equal(t, "Follow", missionTitle(dao.MissionTable, mission, "vi"))
+	require.Equal(t, "Follow", missionTitle(dao.SubMissionTable, mission, "ko"))
+
+	n := &model.UserNotification{
+		Type:   NotificationMissionRevoked,
+		Params: []byte(`{"mission_id":1002,"title":"Follow","title_cn":"关注","credit":5}`),
+	}
+	require.Equal(t, `"팔로우"의 5 크레딧이 취소되었습니다.`, notificationMessage(n, "ko"))
+
+	content, err := mailTemplate("mail.html", "ko")
+	require.NoError(t, err)
+	require.Contains(t, content, "%s")
+}
@@ -146,14 +146,14 @@ func jwtGinMiddleware(secretKey string) (*jwt.GinJWTMiddleware, error) {
 func loginBySignature(c *gin.Context, address, msg, inviteCode string) (interface{}, error) {
 	nonce, err := getNonceFromCache(c.Request.Context(), address, NonceStringTypeSignature)
 	if err != nil {
-		return nil, errors.NewErrorCode(errors.InvalidParams, c)
+		return nil, newErrorCode(errors.InvalidParams, c)
 	}
 	if nonce == "" {
-		return nil, errors.NewErrorCode(errors.VerifyCodeExpired, c)
+		return nil, newErrorCode(errors.VerifyCodeExpired, c)
 	}
 	recoverAddress, err := VerifyMessage(nonce, msg)
 	if strings.ToUpper(recoverAddress) != strings.ToUpper(address) {
-		return nil, errors.NewErrorCode(errors.PassWordNotAllowed, c)
+		return nil, newErrorCode(errors.PassWordNotAllowed, c)
 	}
 	err = AddUserInfo(c.Request.Context(), address, inviteCode)
 	if err != nil {
@@ -166,15 +166,15 @@ func loginByVerifyCode(c *gin.Context, username, inputCode, inviteCode string) (
 	code, err := getNonceFromCache(c.Request.Context(), username, NonceStringTypeLogin)
 	if err != nil {
 		log.Errorf("get user by verify code: %v", err)
-		return nil, errors.NewErrorCode(errors.InvalidParams, c)
+		return nil, newErrorCode(errors.InvalidParams, c)
 	}
 
 	if code == "" {
-		return nil, errors.NewErrorCode(errors.VerifyCodeExpired, c)
+		return nil, newErrorCode(errors.VerifyCodeExpired, c)
 	}
 
 	if code != inputCode {
-		return nil, errors.NewErrorCode(errors.InvalidVerifyCode, c)
+		return nil, newErrorCode(errors.InvalidVerifyCode, c)
 	}
 
 	// _, err = dao.GetUserByUsername(c.Request.Context(), username)
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+var missionStatusNames = map[int32]string{
+	MissionStatusDraft:     "draft",
+	MissionStatusScheduled: "scheduled",
+	MissionStatusActive:    "active",
+	MissionStatusPaused:    "paused",
+	MissionStatusEnded:     "ended",
+	MissionStatusArchived:  "archived",
+}
+
+// missionStatusTransitions 允许的任务状态流转
+var missionStatusTransitions = map[int32][]int32{
+	MissionStatusDraft:     {MissionStatusScheduled, MissionStatusActive, MissionStatusArchived},
+	MissionStatusScheduled: {MissionStatusDraft, MissionStatusActive, MissionStatusPaused, MissionStatusArchived},
+	MissionStatusActive:    {MissionStatusPaused, MissionStatusEnded},
+	MissionStatusPaused:    {MissionStatusActive, MissionStatusEnded, MissionStatusArchived},
+	MissionStatusEnded:     {MissionStatusArchived},
+}
+
+func missionStatusName(status int32) string {
+	return missionStatusNames[status]
+}
+
+func parseMissionStatus(name string) (int32, bool) {
+	for status, n := range missionStatusNames {
+		if strings.EqualFold(n, name) {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+// CanTransitMissionStatus 判断任务状态能否从 from 流转到 to
+func CanTransitMissionStatus(from, to int32) bool {
+	for _, s := range missionStatusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitMissionStatus 修改任务状态, 不允许的流转返回错误
+func TransitMissionStatus(ctx context.Context, mission *model.Mission, to int32) error {
+	return transitMissionStatusIn(ctx, dao.MissionTable, mission, to)
+}
+
+func transitMissionStatusIn(ctx context.Context, table string, mission *model.Mission, to int32) error {
+	if !CanTransitMissionStatus(mission.Status, to) {
+		return fmt.Errorf("mission status cannot transit from %s to %s", missionStatusName(mission.Status), missionStatusName(to))
+	}
+
+	ok, err := dao.UpdateMissionStatusIn(ctx, table, mission.ID, mission.Status, to)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("mission %d status has been changed", mission.ID)
+	}
+
+	mission.Status = to
+	return nil
+}
+
+// missionHasEndTime 结束时间不晚于开始时间时视为不限结束时间
+func missionHasEndTime(mission *model.Mission) bool {
+	return !mission.EndTime.IsZero() && mission.EndTime.After(mission.StartTime)
+}
+
+// missionState 根据任务状态及起止时间计算任务当前所处的状态
+func missionState(mission *model.Mission, now time.Time) int32 {
+	switch mission.Status {
+	case MissionStatusActive, MissionStatusScheduled:
+	default:
+		return mission.Status
+	}
+
+	if missionHasEndTime(mission) && !now.Before(mission.EndTime) {
+		return MissionStatusEnded
+	}
+
+	if !mission.StartTime.IsZero() && now.Before(mission.StartTime) {
+		return MissionStatusScheduled
+	}
+
+	return MissionStatusActive
+}
+
+// missionCountdown 未开始的任务返回距离开始的秒数, 进行中的任务返回距离结束的秒数
+func missionCountdown(mission *model.Mission, state int32, now time.Time) int64 {
+	switch state {
+	case MissionStatusScheduled:
+		return int64(mission.StartTime.Sub(now).Seconds())
+	case MissionStatusActive:
+		if missionHasEndTime(mission) {
+			return int64(mission.EndTime.Sub(now).Seconds())
+		}
+	}
+	return 0
+}
+
+// missionStateErrorCode 任务不可完成时返回对应的错误码, 可以完成时返回 0
+func missionStateErrorCode(state int32) int {
+	switch state {
+	case MissionStatusActive:
+		return 0
+	case MissionStatusEnded, MissionStatusArchived:
+		return errorsx.MissionEnded
+	default:
+		return errorsx.MissionNotActive
+	}
+}
+
+// checkMissionAvailable 校验任务当前能否完成
+func checkMissionAvailable(mission *model.Mission) error {
+	state := missionState(mission, time.Now())
+	if missionStateErrorCode(state) != 0 {
+		return fmt.Errorf("mission %d is %s", mission.ID, missionStatusName(state))
+	}
+	return nil
+}
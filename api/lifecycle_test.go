@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissionState(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.Local)
+
+	mission := &model.Mission{
+		Status:    MissionStatusActive,
+		StartTime: now.Add(time.Hour),
+		EndTime:   now.Add(48 * time.Hour),
+	}
+	require.Equal(t, MissionStatusScheduled, missionState(mission, now))
+	require.EqualValues(t, 3600, missionCountdown(mission, MissionStatusScheduled, now))
+
+	mission.StartTime = now.Add(-time.Hour)
+	require.Equal(t, MissionStatusActive, missionState(mission, now))
+	require.EqualValues(t, 48*3600, missionCountdown(mission, MissionStatusActive, now))
+
+	mission.EndTime = now
+	require.Equal(t, MissionStatusEnded, missionState(mission, now))
+
+	// end time not after start time means the mission never ends
+	mission.EndTime = mission.StartTime
+	require.Equal(t, MissionStatusActive, missionState(mission, now))
+
+	mission.Status = MissionStatusPaused
+	require.Equal(t, MissionStatusPaused, missionState(mission, now))
+}
+
+func TestMissionStatusTransition(t *testing.T) {
+	require.True(t, CanTransitMissionStatus(MissionStatusDraft, MissionStatusActive))
+	require.True(t, CanTransitMissionStatus(MissionStatusActive, MissionStatusPaused))
+	require.True(t, CanTransitMissionStatus(MissionStatusEnded, MissionStatusArchived))
+	require.False(t, CanTransitMissionStatus(MissionStatusEnded, MissionStatusActive))
+	require.False(t, CanTransitMissionStatus(MissionStatusArchived, MissionStatusActive))
+}
+
+func TestMissionSoldOut(t *testing.T) {
+	mission := &model.Mission{Credit: 50}
+	require.False(t, mission.SoldOut(mission.Credit))
+
+	mission.MaxCompletions = 2
+	mission.Completions = 2
+	require.True(t, mission.SoldOut(mission.Credit))
+
+	mission.MaxCompletions = 0
+	mission.CreditBudget = 120
+	mission.CreditsAwarded = 50
+	require.False(t, mission.SoldOut(mission.Credit))
+
+	// 剩余预算不足以发放一次积分
+	mission.CreditsAwarded = 100
+	require.True(t, mission.SoldOut(mission.Credit))
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"bytes"
-	"github.com/gin-gonic/gin"
 	"io"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
 )
 
 func Cors() gin.HandlerFunc {
@@ -31,3 +33,22 @@ func RequestLoggerMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware 可选登录校验, token 有效时写入用户信息, 无效时按未登录继续处理
+func OptionalAuthMiddleware(mw *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := mw.GetClaimsFromJWT(c)
+		if err == nil {
+			if exp, ok := claims["exp"].(float64); ok && int64(exp) >= mw.TimeFunc().Unix() {
+				c.Set("JWT_PAYLOAD", claims)
+			}
+		}
+		c.Next()
+	}
+}
+
+// optionalUsername 获取可选登录的用户名, 未登录时返回空字符串
+func optionalUsername(c *gin.Context) string {
+	username, _ := jwt.ExtractClaims(c)[identityKey].(string)
+	return username
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+const defaultMissionCacheTTL = 60 // 秒
+
+// MissionCatalog 进程内的任务目录, 一次性加载所有任务、子任务、前置任务关系及渠道.
+// 目录加载后不再修改, 获取任务时返回副本, 调用方可以修改
+type MissionCatalog struct {
+	missions    []*model.Mission
+	missionById map[int64]*model.Mission
+	subMissions map[int64][]*model.Mission
+	graph       *MissionGraph
+	channels    []*model.Channel
+	loadedAt    time.Time
+}
+
+// NewMissionCatalog 构建任务目录, 子任务只保留进行中的
+func NewMissionCatalog(missions, subMissions []*model.Mission, edges []*model.MissionPrerequisite, channels []*model.Channel) (*MissionCatalog, error) {
+	graph, err := NewMissionGraph(edges)
+	if err != nil {
+		return nil, err
+	}
+
+	mc := &MissionCatalog{
+		missions:    missions,
+		missionById: make(map[int64]*model.Mission, len(missions)),
+		subMissions: make(map[int64][]*model.Mission),
+		graph:       graph,
+		channels:    channels,
+		loadedAt:    time.Now(),
+	}
+
+	sort.SliceStable(mc.missions, func(i, j int) bool {
+		return mc.missions[i].SortID < mc.missions[j].SortID
+	})
+	for _, m := range mc.missions {
+		mc.missionById[m.ID] = m
+	}
+
+	for _, m := range subMissions {
+		if m.Status != MissionStatusActive {
+			continue
+		}
+		mc.subMissions[m.ParentID] = append(mc.subMissions[m.ParentID], m)
+	}
+	for _, subs := range mc.subMissions {
+		sort.Slice(subs, func(i, j int) bool {
+			return subs[i].ID < subs[j].ID
+		})
+	}
+
+	return mc, nil
+}
+
+func copyMissions(missions []*model.Mission) []*model.Mission {
+	out := make([]*model.Mission, 0, len(missions))
+	for _, m := range missions {
+		c := *m
+		out = append(out, &c)
+	}
+	return out
+}
+
+// Mission 获取任意状态的任务
+func (mc *MissionCatalog) Mission(id int64) (*model.Mission, bool) {
+	m, ok := mc.missionById[id]
+	if !ok {
+		return nil, false
+	}
+
+	c := *m
+	return &c, true
+}
+
+// MissionsByStatus 按排序获取指定状态的任务
+func (mc *MissionCatalog) MissionsByStatus(statuses []int32) []*model.Mission {
+	var out []*model.Mission
+	for _, m := range mc.missions {
+		for _, status := range statuses {
+			if m.Status == status {
+				out = append(out, m)
+				break
+			}
+		}
+	}
+	return copyMissions(out)
+}
+
+// SubMissions 获取任务进行中的子任务
+func (mc *MissionCatalog) SubMissions(parentId int64) []*model.Mission {
+	return copyMissions(mc.subMissions[parentId])
+}
+
+// Graph 获取任务解锁关系图
+func (mc *MissionCatalog) Graph() *MissionGraph {
+	return mc.graph
+}
+
+// Channels 按排序获取渠道
+func (mc *MissionCatalog) Channels() []*model.Channel {
+	return mc.channels
+}
+
+var missionCatalog struct {
+	sync.Mutex
+	current atomic.Pointer[MissionCatalog]
+	stale   atomic.Bool
+}
+
+func missionCacheTTL() time.Duration {
+	ttl := config.Cfg.MissionCacheTTL
+	if ttl <= 0 {
+		ttl = defaultMissionCacheTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// getMissionCatalog 获取任务目录, 收到修改通知或超过缓存时间后重新加载, 并发请求只加载一次
+func getMissionCatalog(ctx context.Context) (*MissionCatalog, error) {
+	if mc := missionCatalog.current.Load(); mc != nil && !missionCatalog.stale.Load() && time.Since(mc.loadedAt) < missionCacheTTL() {
+		return mc, nil
+	}
+
+	missionCatalog.Lock()
+	defer missionCatalog.Unlock()
+
+	if mc := missionCatalog.current.Load(); mc != nil && !missionCatalog.stale.Load() && time.Since(mc.loadedAt) < missionCacheTTL() {
+		return mc, nil
+	}
+
+	// 先清除标记, 加载期间收到的通知会触发下一次加载
+	missionCatalog.stale.Store(false)
+	mc, err := loadMissionCatalog(ctx)
+	if err != nil {
+		missionCatalog.stale.Store(true)
+		return nil, err
+	}
+
+	missionCatalog.current.Store(mc)
+	return mc, nil
+}
+
+func loadMissionCatalog(ctx context.Context) (*MissionCatalog, error) {
+	missions, err := dao.ListAllMissions(ctx, dao.MissionTable, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	subMissions, err := dao.ListAllMissions(ctx, dao.SubMissionTable, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	edges, err := dao.GetMissionPrerequisites(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := dao.GetChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMissionCatalog(missions, subMissions, edges, channels)
+}
+
+// invalidateMissionCatalog 标记任务目录已过期, 下次获取时重新加载
+func invalidateMissionCatalog() {
+	missionCatalog.stale.Store(true)
+}
+
+// StartMissionCatalogSync 订阅任务修改通知, 任意实例修改任务后所有实例重新加载任务目录, 修改文案后重新加载文案
+func StartMissionCatalogSync(ctx context.Context) {
+	pubsub := dao.SubscribeMissionChanged(ctx)
+
+	go func() {
+		defer pubsub.Close()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				if msg.Payload == dao.TranslationTable {
+					if err := loadTranslations(ctx); err != nil {
+						log.Errorf("loadTranslations: %v", err)
+					}
+					continue
+				}
+				invalidateMissionCatalog()
+			}
+		}
+	}()
+}
+
+// getMissionById 获取任意状态的任务, 不存在时返回 sql.ErrNoRows
+func getMissionById(ctx context.Context, id int64) (*model.Mission, error) {
+	mc, err := getMissionCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := mc.Mission(id)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return m, nil
+}
+
+// getActiveMission 获取进行中的任务, 不存在或不是进行中时返回 sql.ErrNoRows
+func getActiveMission(ctx context.Context, id int64) (*model.Mission, error) {
+	m, err := getMissionById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Status != MissionStatusActive {
+		return nil, sql.ErrNoRows
+	}
+
+	return m, nil
+}
+
+// getMissionsByStatus 按排序获取指定状态的任务
+func getMissionsByStatus(ctx context.Context, statuses ...int32) ([]*model.Mission, error) {
+	mc, err := getMissionCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return mc.MissionsByStatus(statuses), nil
+}
+
+// getSubMissions 获取任务进行中的子任务
+func getSubMissions(ctx context.Context, parentId int64) ([]*model.Mission, error) {
+	mc, err := getMissionCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return mc.SubMissions(parentId), nil
+}
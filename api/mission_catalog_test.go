@@ -0,0 +1,50 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissionCatalog(t *testing.T) {
+	missions := []*model.Mission{
+		{ID: 1002, SortID: 2, Status: MissionStatusActive},
+		{ID: 1001, SortID: 1, Status: MissionStatusActive},
+		{ID: 1003, SortID: 3, Status: MissionStatusPaused},
+	}
+	subMissions := []*model.Mission{
+		{ID: 3, ParentID: 1001, Status: MissionStatusActive},
+		{ID: 1, ParentID: 1001, Status: MissionStatusActive},
+		{ID: 2, ParentID: 1001, Status: MissionStatusDraft},
+	}
+	edges := []*model.MissionPrerequisite{{MissionID: 1002, PrerequisiteID: 1001}}
+
+	mc, err := NewMissionCatalog(missions, subMissions, edges, nil)
+	require.NoError(t, err)
+
+	active := mc.MissionsByStatus([]int32{MissionStatusActive})
+	require.Len(t, active, 2)
+	require.EqualValues(t, 1001, active[0].ID)
+	require.EqualValues(t, 1002, active[1].ID)
+
+	subs := mc.SubMissions(1001)
+	require.Len(t, subs, 2)
+	require.EqualValues(t, 1, subs[0].ID)
+	require.EqualValues(t, 3, subs[1].ID)
+
+	require.Equal(t, []int64{1001}, mc.Graph().Prerequisites(1002))
+
+	// callers get copies and can not change the catalog
+	m, ok := mc.Mission(1003)
+	require.True(t, ok)
+	m.Status = MissionStatusActive
+	m, _ = mc.Mission(1003)
+	require.Equal(t, MissionStatusPaused, m.Status)
+
+	_, ok = mc.Mission(1004)
+	require.False(t, ok)
+
+	_, err = NewMissionCatalog(missions, nil, append(edges, &model.MissionPrerequisite{MissionID: 1001, PrerequisiteID: 1002}), nil)
+	require.Error(t, err)
+}
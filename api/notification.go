@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// 站内通知类型
+const (
+	NotificationMissionRecheckFailed = "mission_recheck_failed"
+	NotificationMissionRevoked       = "mission_revoked"
+	NotificationSubmissionApproved   = "submission_approved"
+	NotificationSubmissionRejected   = "submission_rejected"
+)
+
+// notificationParams 通知参数, 读取时按用户语言生成通知内容
+type notificationParams struct {
+	MissionID int64  `json:"mission_id"`
+	Title     string `json:"title"`
+	TitleCn   string `json:"title_cn"`
+	Credit    int64  `json:"credit"`
+	Deadline  int64  `json:"deadline,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// addNotification 添加一条站内通知
+func addNotification(ctx context.Context, username, notificationType string, params notificationParams) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return dao.AddUserNotification(ctx, &model.UserNotification{
+		Username: username,
+		Type:     notificationType,
+		Params:   data,
+	})
+}
+
+// notificationMessage 生成通知内容, 标题优先使用通知时保存的对应语言标题
+func notificationMessage(n *model.UserNotification, locale string) string {
+	var params notificationParams
+	if err := json.Unmarshal(n.Params, &params); err != nil {
+		return ""
+	}
+
+	mission := &model.Mission{ID: params.MissionID, Title: params.Title, TitleCn: params.TitleCn}
+	args := map[string]string{
+		"title":  missionTitle(dao.MissionTable, mission, locale),
+		"credit": strconv.FormatInt(params.Credit, 10),
+		"reason": params.Reason,
+	}
+	if params.Deadline > 0 {
+		args["deadline"] = time.Unix(params.Deadline, 0).UTC().Format("2006-01-02 15:04 MST")
+	}
+
+	key := notificationMessageKey(n.Type)
+	if _, ok := catalog.Lookup(locale, key); !ok {
+		return ""
+	}
+	return catalog.Format(locale, key, args)
+}
+
+// GetNotificationsHandler 获取站内通知
+func GetNotificationsHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	page, _ := c.GetQuery("page")
+	size, _ := c.GetQuery("size")
+	pageInt, _ := strconv.Atoi(page)
+	sizeInt, _ := strconv.Atoi(size)
+
+	out, total, unread, err := dao.GetUserNotifications(c.Request.Context(), username, dao.QueryOption{Page: pageInt, PageSize: sizeInt})
+	if err != nil {
+		log.Errorf("get user notifications error: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	locale := requestLocale(c)
+	list := make([]JsonObject, 0, len(out))
+	for _, n := range out {
+		list = append(list, JsonObject{
+			"id":         n.ID,
+			"type":       n.Type,
+			"params":     n.Params,
+			"message":    notificationMessage(n, locale),
+			"read":       n.Read,
+			"created_at": n.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"total":  total,
+		"unread": unread,
+		"list":   list,
+	}))
+}
+
+// ReadNotificationsHandler 将站内通知标记为已读, 未指定 ids 时标记全部
+func ReadNotificationsHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	var params struct {
+		IDs []int64 `json:"ids"`
+	}
+
+	if err := c.ShouldBindJSON(&params); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	if err := dao.MarkUserNotificationsRead(c.Request.Context(), username, params.IDs); err != nil {
+		log.Errorf("MarkUserNotificationsRead: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(nil))
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// MissionGraph 任务解锁关系图, 任务需要完成所有前置任务后才能解锁
+type MissionGraph struct {
+	prerequisites map[int64][]int64
+}
+
+// NewMissionGraph 根据前置任务关系构建解锁关系图, 存在循环依赖时返回错误
+func NewMissionGraph(edges []*model.MissionPrerequisite) (*MissionGraph, error) {
+	g := &MissionGraph{prerequisites: make(map[int64][]int64)}
+
+	for _, e := range edges {
+		if e.MissionID == e.PrerequisiteID {
+			return nil, fmt.Errorf("mission %d depends on itself", e.MissionID)
+		}
+		g.prerequisites[e.MissionID] = append(g.prerequisites[e.MissionID], e.PrerequisiteID)
+	}
+
+	if err := g.checkCycle(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *MissionGraph) checkCycle() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[int64]int)
+
+	var visit func(id int64, path []int64) error
+	visit = func(id int64, path []int64) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("mission prerequisites contain a cycle: %v", append(path, id))
+		case visited:
+			return nil
+		}
+
+		state[id] = visiting
+		for _, pre := range g.prerequisites[id] {
+			if err := visit(pre, append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+
+		return nil
+	}
+
+	for id := range g.prerequisites {
+		if state[id] == unvisited {
+			if err := visit(id, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Prerequisites 获取任务的直接前置任务
+func (g *MissionGraph) Prerequisites(missionId int64) []int64 {
+	return g.prerequisites[missionId]
+}
+
+// IsUnlocked 判断用户在已完成任务集合下是否解锁了该任务
+func (g *MissionGraph) IsUnlocked(missionId int64, completed map[int64]struct{}) bool {
+	for _, pre := range g.prerequisites[missionId] {
+		if _, ok := completed[pre]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func loadMissionGraph(ctx context.Context) (*MissionGraph, error) {
+	mc, err := getMissionCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return mc.Graph(), nil
+}
+
+func getUserCompletedMissions(ctx context.Context, username string) (map[int64]struct{}, error) {
+	completed := make(map[int64]struct{})
+	if username == "" {
+		return completed, nil
+	}
+
+	ids, err := dao.GetUserCompletedMissionIds(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		completed[id] = struct{}{}
+	}
+
+	return completed, nil
+}
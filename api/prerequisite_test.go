@@ -0,0 +1,38 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissionGraph(t *testing.T) {
+	g, err := NewMissionGraph([]*model.MissionPrerequisite{
+		{MissionID: MissionIdRetweet, PrerequisiteID: MissionIdFollowTwitter},
+		{MissionID: MissionIdJoinDCVolunteerChannel, PrerequisiteID: MissionIdBecomeVolunteer},
+	})
+	require.NoError(t, err)
+
+	completed := map[int64]struct{}{}
+	require.False(t, g.IsUnlocked(MissionIdRetweet, completed))
+	require.True(t, g.IsUnlocked(MissionIdFollowTwitter, completed))
+
+	completed[MissionIdFollowTwitter] = struct{}{}
+	require.True(t, g.IsUnlocked(MissionIdRetweet, completed))
+	require.False(t, g.IsUnlocked(MissionIdJoinDCVolunteerChannel, completed))
+}
+
+func TestMissionGraphCycle(t *testing.T) {
+	_, err := NewMissionGraph([]*model.MissionPrerequisite{
+		{MissionID: 1, PrerequisiteID: 2},
+		{MissionID: 2, PrerequisiteID: 3},
+		{MissionID: 3, PrerequisiteID: 1},
+	})
+	require.Error(t, err)
+
+	_, err = NewMissionGraph([]*model.MissionPrerequisite{
+		{MissionID: 1, PrerequisiteID: 1},
+	})
+	require.Error(t, err)
+}
@@ -3,10 +3,10 @@ package api
 import (
 	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	tele "gopkg.in/telebot.v3"
@@ -17,7 +17,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/antihax/optional"
 	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gin-gonic/gin"
@@ -25,9 +24,8 @@ import (
 	"github.com/gnasnik/titan-quest/core/dao"
 	errorsx "github.com/gnasnik/titan-quest/core/errors"
 	"github.com/gnasnik/titan-quest/core/generated/model"
-	"github.com/gnasnik/titan-quest/core/opcrypt"
-	swagger "github.com/gnasnik/titan-quest/go-client-generated"
 	"github.com/gnasnik/titan-quest/pkg/random"
+	"github.com/go-redis/redis/v9"
 	"github.com/golang-module/carbon/v2"
 	"github.com/mrjones/oauth"
 	"github.com/valyala/fastjson"
@@ -287,138 +285,230 @@ func TwitterCallBackHandler(c *gin.Context) {
 
 type RespMission struct {
 	*model.Mission
-	SubMission []*model.Mission `json:"sub_mission"`
+	SubMission    []*model.Mission `json:"sub_mission"`
+	Prerequisites []int64          `json:"prerequisites"`
+	Locked        bool             `json:"locked"`
+	State         string           `json:"state"`
+	Countdown     int64            `json:"countdown"`
+	SoldOut       bool             `json:"sold_out"`
+	// 人工审核任务在当前周期的审核状态, 未提交时为空
+	Review *RespReview `json:"review,omitempty"`
+}
+
+// parseMissionStateFilter 解析任务列表的状态过滤参数, 默认只返回进行中的任务, 草稿不对外展示
+func parseMissionStateFilter(value string) (map[int32]struct{}, error) {
+	states := make(map[int32]struct{})
+	if strings.TrimSpace(value) == "" {
+		states[MissionStatusActive] = struct{}{}
+		return states, nil
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		status, ok := parseMissionStatus(strings.TrimSpace(name))
+		if !ok || status == MissionStatusDraft {
+			return nil, fmt.Errorf("invalid mission state: %s", name)
+		}
+		states[status] = struct{}{}
+	}
+
+	return states, nil
 }
 
 func QueryMissionHandler(c *gin.Context) {
-	missions, err := dao.GetMissions(c.Request.Context())
+	username := optionalUsername(c)
+
+	states, err := parseMissionStateFilter(c.Query("state"))
 	if err != nil {
-		log.Errorf("GetMissions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	seasonId, err := querySeasonId(c)
+	if err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	// 进行中和未开始的任务会随时间流转, 需要一起查询后再按当前状态过滤
+	statuses := []int32{MissionStatusActive, MissionStatusScheduled}
+	for status := range states {
+		statuses = append(statuses, status)
+	}
+
+	missions, err := getMissionsByStatus(c.Request.Context(), statuses...)
+	if err != nil {
+		log.Errorf("getMissionsByStatus: %v", err)
 		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 		return
 	}
 
-	var (
-		basicMissions    []*RespMission
-		twitterMissions  []*RespMission
-		discordMissions  []*RespMission
-		telegramMissions []*RespMission
-	)
+	graph, err := loadMissionGraph(c.Request.Context())
+	if err != nil {
+		log.Errorf("loadMissionGraph: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	completed, err := getUserCompletedMissions(c.Request.Context(), username)
+	if err != nil {
+		log.Errorf("getUserCompletedMissions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	mc, err := getMissionCatalog(c.Request.Context())
+	if err != nil {
+		log.Errorf("getMissionCatalog: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	locale := requestLocale(c)
+	channels := newChannelGrouper(mc.Channels(), locale)
+
+	now := time.Now()
+	reviews, err := getUserReviews(c.Request.Context(), username, missions, now)
+	if err != nil {
+		log.Errorf("getUserReviews: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
 
 	for _, mission := range missions {
-		subMission, err := dao.GetSubMissions(c.Request.Context(), mission.ID)
+		if !inSeason(mission, seasonId) {
+			continue
+		}
+
+		state := missionState(mission, now)
+		if _, ok := states[state]; !ok {
+			continue
+		}
+
+		subMission, err := getSubMissions(c.Request.Context(), mission.ID)
 		if err != nil {
-			log.Errorf("GetSubMissions: %v", err)
+			log.Errorf("getSubMissions: %v", err)
 			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 			return
 		}
+		subMission = seasonSubMissions(subMission, seasonId)
 
-		mi := &RespMission{
-			Mission:    mission,
-			SubMission: subMission,
+		for i, sub := range subMission {
+			subMission[i] = localizeMission(dao.SubMissionTable, sub, locale)
 		}
 
-		// 处理浏览官网跳转
-
-		switch mission.Channel {
-		case "Wallet", "Titan":
-			basicMissions = append(basicMissions, mi)
-		case "Twitter":
-			twitterMissions = append(twitterMissions, mi)
-		case "Discord":
-			discordMissions = append(discordMissions, mi)
-		case "Telegram":
-			telegramMissions = append(telegramMissions, mi)
+		mi := &RespMission{
+			Mission:       localizeMission(dao.MissionTable, mission, locale),
+			SubMission:    subMission,
+			Prerequisites: graph.Prerequisites(mission.ID),
+			Locked:        !graph.IsUnlocked(mission.ID, completed),
+			State:         missionStatusName(state),
+			Countdown:     missionCountdown(mission, state, now),
+			SoldOut:       mission.SoldOut(mission.Credit),
+			Review:        reviews[mission.ID],
 		}
+
+		channels.Add(mission.Channel, mi)
 	}
 
-	c.JSON(http.StatusOK, respJSON(JsonObject{
-		"basic_missions":    basicMissions,
-		"twitter_missions":  twitterMissions,
-		"discord_missions":  discordMissions,
-		"telegram_missions": telegramMissions,
-	}))
+	out := channels.Groups()
+	out["season_id"] = seasonId
+	out["channels"] = channels.Channels()
+
+	c.JSON(http.StatusOK, respJSON(out))
 }
 
 func QueryUserCreditsHandler(c *gin.Context) {
 	claims := jwt.ExtractClaims(c)
 	username := claims[identityKey].(string)
 
-	// 获取已完成的基础任务
-	completeBasicMission, err := dao.GetUserMissionByUser(c.Request.Context(), username, 1, dao.QueryOption{})
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		log.Errorf("GetUserMissionByUser: %v", err)
+	missions, err := getMissionsByStatus(c.Request.Context(), MissionStatusActive)
+	if err != nil {
+		log.Errorf("getMissionsByStatus: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
 	}
 
-	var basicUserMission []*model.UserMission
-	for _, bs := range completeBasicMission {
-		if bs.MissionID == MissionIdLikeTwitter || bs.MissionID == MissionIdRetweet {
-			mission, err := dao.GetMissionById(c.Request.Context(), bs.MissionID)
-			if err != nil {
-				log.Errorf("GetMissionById: %v", err)
-				c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
-				return
-			}
-
-			if mission.OpenUrl != bs.Content {
-				continue
-			}
-		}
-
-		basicUserMission = append(basicUserMission, bs)
+	missionMap := make(map[int64]*model.Mission)
+	for _, mission := range missions {
+		missionMap[mission.ID] = mission
 	}
 
-	// 获取已完成的每日任务
-	dailyUserMission, err := dao.GetUserMissionByUser(c.Request.Context(), username, 2, dao.QueryOption{StartTime: carbon.Now().StartOfDay().String()})
+	completeUserMission, err := dao.GetUserMissions(c.Request.Context(), username)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		log.Errorf("GetUserMissionByUser: %v", err)
+		log.Errorf("GetUserMissions: %v", err)
 	}
 
-	// 获取已完成的每周任务
-	//weeklyMission, err := dao.GetUserMissionByUser(c.Request.Context(), username, 3, dao.QueryOption{StartTime: carbon.Now().StartOfWeek().String()})
-	//if err != nil && !errors.Is(err, sql.ErrNoRows) {
-	//	log.Errorf("GetUserMissionByUser: %v", err)
-	//}
+	mc, err := getMissionCatalog(c.Request.Context())
+	if err != nil {
+		log.Errorf("getMissionCatalog: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
 
-	var (
-		basicMissions    []*model.UserMission
-		twitterMissions  []*model.UserMission
-		discordMissions  []*model.UserMission
-		telegramMissions []*model.UserMission
-	)
+	channels := newChannelGrouper(mc.Channels(), requestLocale(c))
+
+	now := time.Now()
+	for _, um := range completeUserMission {
+		mission, ok := missionMap[um.MissionID]
+		if !ok {
+			continue
+		}
 
-	for _, um := range append(basicUserMission, dailyUserMission...) {
-		mission, err := dao.GetMissionById(c.Request.Context(), um.MissionID)
+		if isPerTweetVerifier(mission.Verifier) && mission.OpenUrl != um.Content {
+			continue
+		}
+
+		// 只返回当前周期内完成的记录
+		window, err := missionWindow(mission, now)
 		if err != nil {
+			log.Errorf("missionWindow: %d %v", mission.ID, err)
 			continue
 		}
 
-		switch mission.Channel {
-		case "Wallet", "Titan":
-			basicMissions = append(basicMissions, um)
-		case "Twitter":
-			twitterMissions = append(twitterMissions, um)
-		case "Discord":
-			discordMissions = append(discordMissions, um)
-		case "Telegram":
-			telegramMissions = append(telegramMissions, um)
+		if !window.Contains(um.CreatedAt) {
+			continue
 		}
+
+		channels.Add(mission.Channel, um)
 	}
 
-	credits, err := dao.SumUserCredits(c.Request.Context(), username)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		log.Errorf("SumUserCredits: %v", err)
+	balance, err := dao.GetCreditBalance(c.Request.Context(), username)
+	if err != nil {
+		log.Errorf("GetCreditBalance: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
 	}
 
-	icredits, err := dao.SumInviteCredits(c.Request.Context(), username)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		log.Errorf("SumUserCredits: %v", err)
+	seasonId, err := currentSeasonId(c.Request.Context())
+	if err != nil {
+		log.Errorf("currentSeasonId: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	var seasonCredits int64
+	if seasonId > 0 {
+		sc, err := dao.GetSeasonCredit(c.Request.Context(), seasonId, username)
+		if err != nil {
+			log.Errorf("GetSeasonCredit: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+		seasonCredits = sc.Credits
+	}
+
+	streaks, err := getUserStreaks(c.Request.Context(), username, now)
+	if err != nil {
+		log.Errorf("getUserStreaks: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
 	}
 
 	var (
 		twitterUserId  string
 		discordUserId  string
 		telegramUserId int64
+		githubLogin    string
 	)
 	twitterUser, err := dao.GetTwitterOauthByUsername(c.Request.Context(), username)
 	if twitterUser != nil {
@@ -435,19 +525,25 @@ func QueryUserCreditsHandler(c *gin.Context) {
 		telegramUserId = telegramUser.TelegramUserID
 	}
 
+	githubUser, err := dao.GetGithubOAuthByUsername(c.Request.Context(), username)
+	if githubUser != nil {
+		githubLogin = githubUser.GithubLogin
+	}
+
 	c.JSON(http.StatusOK, respJSON(JsonObject{
 		"address":          username,
-		"credits":          credits,
-		"invite_credits":   icredits,
+		"credits":          balance.MissionCredits,
+		"invite_credits":   balance.InviteCredits,
+		"balance":          balance.Balance,
+		"season_id":        seasonId,
+		"season_credits":   seasonCredits,
 		"twitter_user_id":  twitterUserId,
 		"discord_user_id":  discordUserId,
 		"telegram_user_id": telegramUserId,
-		"missions": JsonObject{
-			"basic_missions":    basicMissions,
-			"twitter_missions":  twitterMissions,
-			"discord_missions":  discordMissions,
-			"telegram_missions": telegramMissions,
-		},
+		"github_login":     githubLogin,
+		"streaks":          streaks,
+		"missions":         channels.Groups(),
+		"channels":         channels.Channels(),
 	}))
 }
 
@@ -456,30 +552,47 @@ func CheckQuestHandler(c *gin.Context) {
 	username := claims[identityKey].(string)
 	missionId, _ := strconv.ParseInt(c.Query("mission_id"), 10, 64)
 
-	mission, err := dao.GetMissionById(c.Request.Context(), missionId)
+	mission, err := getMissionById(c.Request.Context(), missionId)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
 	if err != nil {
-		log.Errorf("GetMissionById: %v", err)
+		log.Errorf("getMissionById: %v", err)
 		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 		return
 	}
 
-	if mission == nil {
-		log.Errorf("mission not found")
+	graph, err := loadMissionGraph(c.Request.Context())
+	if err != nil {
+		log.Errorf("loadMissionGraph: %v", err)
 		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 		return
 	}
 
-	option := dao.QueryOption{}
-	if missionId == MissionIdRetweet || missionId == MissionIdLikeTwitter {
-		option.Content = mission.OpenUrl
+	completed, err := getUserCompletedMissions(c.Request.Context(), username)
+	if err != nil {
+		log.Errorf("getUserCompletedMissions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
 	}
 
-	if mission.Type == MissionTypeDaily {
-		option.StartTime = carbon.Now().StartOfDay().String()
+	if !graph.IsUnlocked(mission.ID, completed) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.MissionLocked, c))
+		return
+	}
+
+	window, err := missionWindow(mission, time.Now())
+	if err != nil {
+		log.Errorf("missionWindow: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
 	}
 
-	if mission.Type == MissionTypeWeekly {
-		option.StartTime = carbon.Now().StartOfWeek().String()
+	option := windowQueryOption(window)
+	if isPerTweetVerifier(mission.Verifier) {
+		option.Content = mission.OpenUrl
 	}
 
 	ums, err := dao.GetUserMissionByMissionId(c.Request.Context(), username, missionId, option)
@@ -491,8 +604,8 @@ func CheckQuestHandler(c *gin.Context) {
 
 	expectedCount := 1
 
-	if missionId == MissionIdInviteFriendsToDiscord {
-		subMissions, err := dao.GetSubMissions(c.Request.Context(), missionId)
+	if mission.Verifier == VerifierInviteFriendsToDiscord {
+		subMissions, err := getSubMissions(c.Request.Context(), missionId)
 		if err != nil {
 			log.Errorf("GetUserMissionByMissionId: %v", err)
 			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
@@ -508,56 +621,82 @@ func CheckQuestHandler(c *gin.Context) {
 		return
 	}
 
-	switch missionId {
-	case MissionIdFollowTwitter:
-		err = checkFollowTwitter(c.Request.Context(), mission, username, option)
-	case MissionIdRetweet:
-		err = checkReTweet(c.Request.Context(), mission, username, option)
-	case MissionIdLikeTwitter:
-		err = checkLikeTweet(c.Request.Context(), mission, username, option)
-	case MissionIdJoinDiscord:
-		err = checkJoinDiscord(c.Request.Context(), mission, username, option)
-	case MissionIdBindingKOL:
-		err = checkBindingKOL(c.Request.Context(), mission, username, option)
-	case MissionIdVisitOfficialWebsite:
-		err = checkVisitOfficialWebsite(c.Request.Context(), mission, username, option)
-	case MissionIdVisitReferrerPage:
-		err = checkVisitReferrerPage(c.Request.Context(), mission, username, option)
-	case MissionIdJoinTelegramGroup, MissionIdJoinTelegramVolunteerGroup:
-		err = checkJoinTelegram(c.Request.Context(), mission, username, option)
-	case MissionIdQuoteTweet:
-		err = checkQuoteTweet(c.Request.Context(), mission, username, option)
-	case MissionIdPostTweet:
-		err = checkPostTweet(c.Request.Context(), mission, username, option)
-	case MissionIdInviteFriendsToDiscord:
-		err = checkInviteFriendsToDiscord(c.Request.Context(), mission, username, option)
-	case MissionIdJoinDCVolunteerChannel:
-		err = checkJoinVolunteerChannel(c.Request.Context(), mission, username, option)
-	default:
+	// 已结束的任务不再校验和发放积分
+	if code := missionStateErrorCode(missionState(mission, time.Now())); code != 0 {
+		c.JSON(http.StatusOK, respErrorCode(code, c))
+		return
+	}
+
+	if mission.SoldOut(mission.Credit) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.MissionSoldOut, c))
+		return
+	}
+
+	if _, ok := GetVerifier(mission); !ok {
 		c.JSON(http.StatusOK, respErrorCode(errorsx.NoImplement, c))
 		return
 	}
 
+	// 校验需要调用第三方接口, 提交异步作业后由客户端轮询结果
+	job, err := submitVerifyJob(c.Request.Context(), username, mission, option)
 	if err != nil {
-		log.Errorf("check mission: %v", err)
-		c.JSON(http.StatusOK, respErrorCode(errorsx.MissionUnComplete, c))
+		log.Errorf("submitVerifyJob: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 		return
 	}
 
-	ums, err = dao.GetUserMissionByMissionId(c.Request.Context(), username, missionId, option)
-	if err != nil {
-		log.Errorf("GetUserMissionByMissionId: %v", err)
+	c.JSON(http.StatusOK, respJSON(verifyJobResponse(job, nil, c)))
+}
+
+// CheckQuestStatusHandler 查询任务校验作业的结果, wait 参数指定等待作业结束的秒数
+func CheckQuestStatusHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+	wait, _ := strconv.ParseInt(c.Query("wait"), 10, 64)
+
+	job, err := waitVerifyJob(c.Request.Context(), c.Query("job_id"), time.Duration(wait)*time.Second)
+	if err == redis.Nil || (err == nil && job.Username != username) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
 		return
 	}
 
-	if len(ums) > 0 {
-		c.JSON(http.StatusOK, respJSON(JsonObject{
-			"missions": ums,
-		}))
+	if err != nil {
+		log.Errorf("waitVerifyJob: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 		return
 	}
 
-	c.JSON(http.StatusOK, respJSON(nil))
+	var ums []*model.UserMission
+	if job.Status == VerifyJobStatusSucceeded {
+		ums, err = dao.GetUserMissionByMissionId(c.Request.Context(), username, job.MissionID, verifyJobQueryOption(job))
+		if err != nil {
+			log.Errorf("GetUserMissionByMissionId: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, respJSON(verifyJobResponse(job, ums, c)))
+}
+
+func verifyJobResponse(job *model.VerifyJob, ums []*model.UserMission, c *gin.Context) JsonObject {
+	out := JsonObject{
+		"job_id":     job.ID,
+		"mission_id": job.MissionID,
+		"status":     job.Status,
+		"attempts":   job.Attempts,
+	}
+
+	if job.ErrCode != 0 {
+		out["err"] = job.ErrCode
+		out["msg"] = errorMessage(job.ErrCode, requestLocale(c))
+	}
+
+	if ums != nil {
+		out["missions"] = ums
+	}
+
+	return out
 }
 
 var globalCounter int
@@ -574,9 +713,9 @@ func GetUToolKeyByRoundRobin() string {
 }
 
 func completeConnectWalletMission(ctx context.Context, address string) error {
-	mission, err := dao.GetMissionById2(ctx, MissionIdConnectWallet)
+	mission, err := getMissionById(ctx, MissionIdConnectWallet)
 	if err != nil {
-		log.Errorf("GetMissionById: %v", err)
+		log.Errorf("getMissionById: %v", err)
 		return err
 	}
 
@@ -615,55 +754,13 @@ func checkFollowTwitter(ctx context.Context, mission *model.Mission, username st
 		return err
 	}
 
-	client := swagger.NewAPIClient(swagger.NewConfiguration())
-
-	apiKey := GetUToolKeyByRoundRobin()
-
-	option := &swagger.TwitterFollowsApiToolsApiFollowingsIdsUsingGETOpts{
-		UserId: optional.NewString(twitterUser.TwitterUserID),
-	}
-	result, _, err := client.TwitterFollowsApiToolsApi.FollowingsIdsUsingGET(ctx, apiKey, option)
+	followed, err := isFollowing(ctx, twitterUser.TwitterUserID, config.Cfg.OfficialTwitterUserId)
 	if err != nil {
-		log.Errorf("FollowingsIdsUsingGET: %v", err)
 		return err
 	}
 
-	if result.Code != 1 {
-		log.Errorf("code: %d %s", result.Code, result.Msg)
-		return errors.New(result.Msg)
-	}
-
-	type followIdsResp struct {
-		PreviousCursor       int64   `json:"previous_cursor"`
-		Ids                  []int64 `json:"ids"`
-		PreviousCursorString string  `json:"previous_cursor_str"`
-		NextCursor           int64   `json:"next_cursor"`
-		NextCursorStr        string  `json:"next_cursor_str"`
-	}
-
-	bytesString, ok := result.Data.(string)
-	if !ok {
-		return errors.New("response not string")
-	}
-
-	var resp followIdsResp
-	if err := json.Unmarshal([]byte(bytesString), &resp); err != nil {
-		return err
-	}
-
-	var followed bool
-
-	for _, id := range resp.Ids {
-		if id != config.Cfg.OfficialTwitterUserId {
-			continue
-		}
-
-		followed = true
-		break
-	}
-
 	if !followed {
-		return errors.New("user unfollow")
+		return verificationFailed("user unfollow")
 	}
 
 	ums, err := dao.GetUserMissionByMissionId(ctx, username, mission.ID, queryOpt)
@@ -679,6 +776,7 @@ func checkFollowTwitter(ctx context.Context, mission *model.Mission, username st
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   twitterUser.TwitterUserID,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 		// err = dao.AddUserMission(ctx, &model.UserMission{
@@ -706,55 +804,22 @@ func checkLikeTweet(ctx context.Context, mission *model.Mission, username string
 		return err
 	}
 
-	openURL, err := url.Parse(mission.OpenUrl)
+	params, err := mission.GetParams()
 	if err != nil {
-		log.Errorf("Parse OPEN URL: %v", err)
+		log.Errorf("GetParams: %v", err)
 		return err
 	}
 
-	tweetId := openURL.Query().Get("tweet_id")
-	client := swagger.NewAPIClient(swagger.NewConfiguration())
-
-	apiKey := GetUToolKeyByRoundRobin()
-	option := &swagger.TwitterGetTweesApiToolsApiFavoritersV2UsingGETOpts{}
-
-	if tweetId == "" {
+	if params.TweetID == "" {
 		return errors.New("missing tweet id")
 	}
 
-	result, _, err := client.TwitterGetTweesApiToolsApi.FavoritersV2UsingGET(ctx, apiKey, tweetId, option)
+	liked, err := hasFavorited(ctx, params.TweetID, twitterUser.TwitterUserID)
 	if err != nil {
-		log.Errorf("GetListByUserIdOrScreenNameUsingGET: %v", err)
+		log.Errorf("hasFavorited: %v", err)
 		return err
 	}
 
-	if result.Code != 1 {
-		log.Errorf("code: %d %s", result.Code, result.Msg)
-		return errors.New(result.Msg)
-	}
-
-	v, err := fastjson.Parse(result.Data.(string))
-	if err != nil {
-		return errors.New(err.Error())
-	}
-
-	var liked bool
-
-	entries := v.Get("data").Get("favoriters_timeline").Get("timeline").Get("instructions", "0").GetArray("entries")
-
-	fmt.Println("==>", v.Get("data"))
-
-	for _, e := range entries {
-		twitterUserId := e.Get("content").Get("itemContent").Get("user_results").Get("result").GetStringBytes("rest_id")
-
-		if string(twitterUserId) != twitterUser.TwitterUserID {
-			continue
-		}
-
-		liked = true
-		break
-	}
-
 	if !liked {
 		return errors.New("mission uncompleted")
 	}
@@ -772,6 +837,7 @@ func checkLikeTweet(ctx context.Context, mission *model.Mission, username string
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   mission.OpenUrl,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 		// err = dao.AddUserMission(ctx, &model.UserMission{
@@ -799,55 +865,23 @@ func checkReTweet(ctx context.Context, mission *model.Mission, username string,
 		return err
 	}
 
-	client := swagger.NewAPIClient(swagger.NewConfiguration())
-
-	apiKey := GetUToolKeyByRoundRobin()
-	option := &swagger.TwitterGetTweesApiToolsApiRetweetersV2UsingGETOpts{}
-
-	openURL, err := url.Parse(mission.OpenUrl)
+	params, err := mission.GetParams()
 	if err != nil {
-		log.Errorf("Parse OPEN URL: %v", err)
+		log.Errorf("GetParams: %v", err)
 		return err
 	}
 
-	tweetId := openURL.Query().Get("tweet_id")
-
-	if tweetId == "" {
+	if params.TweetID == "" {
 		return errors.New("missing tweet id")
 	}
 
-	result, _, err := client.TwitterGetTweesApiToolsApi.RetweetersV2UsingGET(ctx, apiKey, tweetId, option)
+	retweeted, err := hasRetweeted(ctx, params.TweetID, twitterUser.TwitterUserID)
 	if err != nil {
-		log.Errorf("GetListByUserIdOrScreenNameUsingGET: %v", err)
+		log.Errorf("hasRetweeted: %v", err)
 		return err
 	}
 
-	if result.Code != 1 {
-		log.Errorf("code: %d %s", result.Code, result.Msg)
-		return errors.New(result.Msg)
-	}
-
-	v, err := fastjson.Parse(result.Data.(string))
-	if err != nil {
-		return errors.New(err.Error())
-	}
-
-	var liked bool
-
-	entries := v.Get("data").Get("retweeters_timeline").Get("timeline").Get("instructions", "0").GetArray("entries")
-
-	for _, e := range entries {
-		twitterUserId := e.Get("content").Get("itemContent").Get("user_results").Get("result").GetStringBytes("rest_id")
-
-		if string(twitterUserId) != twitterUser.TwitterUserID {
-			continue
-		}
-
-		liked = true
-		break
-	}
-
-	if !liked {
+	if !retweeted {
 		return errors.New("mission uncompleted")
 	}
 
@@ -864,6 +898,7 @@ func checkReTweet(ctx context.Context, mission *model.Mission, username string,
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   mission.OpenUrl,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 
@@ -876,6 +911,7 @@ func checkReTweet(ctx context.Context, mission *model.Mission, username string,
 	return nil
 }
 
+// checkQuoteTweet 校验用户是否引用了目标推文, 优先校验用户提交的链接, 未提交链接或链接不符合要求时查询目标推文的引用
 func checkQuoteTweet(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
 	twitterUser, err := dao.GetTwitterOauthByUsername(ctx, username)
 	if err != nil {
@@ -883,71 +919,36 @@ func checkQuoteTweet(ctx context.Context, mission *model.Mission, username strin
 		return err
 	}
 
-	client := swagger.NewAPIClient(swagger.NewConfiguration())
-	twitterLink, err := dao.GetUserTwitterLink(ctx, username, mission.ID, carbon.Now().StartOfDay().String())
-	if err != nil {
-		log.Errorf("GetUserTwitterLink: %v", err)
-		return err
-	}
-
-	replyURL, err := url.Parse(twitterLink.Link)
+	params, err := mission.GetParams()
 	if err != nil {
-		log.Errorf("Parse twitter Link URL: %v", err)
+		log.Errorf("GetParams: %v", err)
 		return err
 	}
 
-	paths := strings.Split(replyURL.Path, "/")
-
-	if len(paths) != 4 {
-		log.Errorf("Invalid URL: %v", err)
-		return errors.New("invalid url")
-	}
-
-	replyId := strings.TrimSpace(paths[len(paths)-1])
+	since := periodStart(queryOpt)
 
-	if replyId == "" {
-		return errors.New("missing tweet id")
-	}
+	var quote *fastjson.Value
+	twitterLink, err := dao.GetUserTwitterLink(ctx, username, mission.ID, dao.QueryOption{StartTime: queryOpt.StartTime, EndTime: queryOpt.EndTime})
+	switch {
+	case err == nil:
+		quote, err = getLinkedTweet(ctx, twitterLink.Link)
+		if err != nil && isTransientError(err) {
+			return err
+		}
 
-	apiKey := GetUToolKeyByRoundRobin()
-	option := &swagger.TwitterGetTweesApiToolsApiTweetTimelineUsingGETOpts{}
-	result, _, err := client.TwitterGetTweesApiToolsApi.TweetTimelineUsingGET(ctx, apiKey, replyId, option)
-	if err != nil {
-		log.Errorf("GetListByUserIdOrScreenNameUsingGET: %v", err)
+		if err != nil || !matchQuote(quote, twitterUser.TwitterUserID, params, since) {
+			log.Warnf("invalid quote link %s: %v", twitterLink.Link, err)
+			quote = nil
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		log.Errorf("GetUserTwitterLink: %v", err)
 		return err
 	}
 
-	if result.Code != 1 {
-		log.Errorf("code: %d %s", result.Code, result.Msg)
-		return errors.New(result.Msg)
-	}
-
-	v, err := fastjson.Parse(result.Data.(string))
-	if err != nil {
-		return errors.New(err.Error())
-	}
-
-	entries := v.Get("data").Get("threaded_conversation_with_injections_v2").Get("instructions", "0").Get("entries", "0")
-	res := entries.Get("content").Get("itemContent").Get("tweet_results").Get("result")
-	postUserId := res.Get("core").Get("user_results").Get("result").GetStringBytes("rest_id")
-	sourceTweetId := res.Get("quoted_status_result").Get("result").GetStringBytes("rest_id")
-	mentions := res.Get("legacy").Get("entities").GetArray("user_mentions")
-	postCreatedAt := res.Get("legacy").GetStringBytes("created_at")
-
-	if !strings.Contains(mission.OpenUrl, string(sourceTweetId)) {
-		return errors.New("not allowed source tweet id")
-	}
-
-	if string(postUserId) != twitterUser.TwitterUserID {
-		return errors.New(fmt.Sprintf("invalid link, expected twitter user id: %s got: %s", twitterUser.TwitterUserID, string(postUserId)))
-	}
-
-	if carbon.Parse(string(postCreatedAt)).Lt(carbon.Now().StartOfDay()) {
-		return errors.New("post expiration")
-	}
-
-	if len(mentions) < 3 {
-		return errors.New("tag users not enough")
+	if quote == nil {
+		if _, err = findQuoteTweet(ctx, twitterUser.TwitterUserID, params, since); err != nil {
+			return err
+		}
 	}
 
 	ums, err := dao.GetUserMissionByMissionId(ctx, username, mission.ID, queryOpt)
@@ -963,6 +964,7 @@ func checkQuoteTweet(ctx context.Context, mission *model.Mission, username strin
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   twitterUser.TwitterUserID,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 
@@ -982,77 +984,42 @@ func checkPostTweet(ctx context.Context, mission *model.Mission, username string
 		return err
 	}
 
-	openURL, err := url.Parse(mission.OpenUrl)
+	params, err := mission.GetParams()
 	if err != nil {
-		log.Errorf("Parse OPEN URL: %v", err)
+		log.Errorf("GetParams: %v", err)
 		return err
 	}
 
-	copyContent := openURL.Query().Get("text")
-
-	client := swagger.NewAPIClient(swagger.NewConfiguration())
-	twitterLink, err := dao.GetUserTwitterLink(ctx, username, mission.ID, carbon.Now().StartOfDay().String())
-	if err != nil {
-		log.Errorf("GetUserTwitterLink: %v", err)
+	// 未配置推文内容要求时任何推文都能通过校验, 不予校验
+	if err := requireTweetContent(mission, params); err != nil {
 		return err
 	}
 
-	replyURL, err := url.Parse(twitterLink.Link)
+	twitterLink, err := dao.GetUserTwitterLink(ctx, username, mission.ID, dao.QueryOption{StartTime: queryOpt.StartTime, EndTime: queryOpt.EndTime})
 	if err != nil {
-		log.Errorf("Parse twitter Link URL: %v", err)
-		return err
-	}
-
-	paths := strings.Split(replyURL.Path, "/")
-
-	if len(paths) != 4 {
-		log.Errorf("Invalid URL: %v", err)
-		return errors.New("invalid url")
-	}
-
-	replyId := strings.TrimSpace(paths[len(paths)-1])
-
-	if replyId == "" {
-		return errors.New("missing tweet id")
-	}
-
-	apiKey := GetUToolKeyByRoundRobin()
-	option := &swagger.TwitterGetTweesApiToolsApiTweetTimelineUsingGETOpts{}
-	result, _, err := client.TwitterGetTweesApiToolsApi.TweetTimelineUsingGET(ctx, apiKey, replyId, option)
-	if err != nil {
-		log.Errorf("GetListByUserIdOrScreenNameUsingGET: %v", err)
+		log.Errorf("GetUserTwitterLink: %v", err)
 		return err
 	}
 
-	if result.Code != 1 {
-		log.Errorf("code: %d %s", result.Code, result.Msg)
-		return errors.New(result.Msg)
-	}
-
-	v, err := fastjson.Parse(result.Data.(string))
+	res, err := getLinkedTweet(ctx, twitterLink.Link)
 	if err != nil {
-		log.Errorf("parse data: %v", err)
+		log.Errorf("getLinkedTweet: %v", err)
 		return err
 	}
 
-	entries := v.Get("data").Get("threaded_conversation_with_injections_v2").Get("instructions", "0").Get("entries", "0")
-	res := entries.Get("content").Get("itemContent").Get("tweet_results").Get("result")
-	postUserId := res.Get("core").Get("user_results").Get("result").GetStringBytes("rest_id")
-	fullText := res.Get("legacy").GetStringBytes("full_text")
+	postUserId := tweetAuthorId(res)
 	postCreatedAt := res.Get("legacy").GetStringBytes("created_at")
 
-	FirstLine := strings.Split(string(fullText), "\n")[0]
-
-	if !strings.Contains(copyContent, FirstLine) {
-		return errors.New("invalid content")
+	if err := checkTweetContent(res, params); err != nil {
+		return err
 	}
 
-	if carbon.Parse(string(postCreatedAt)).Lt(carbon.Now().StartOfDay()) {
+	if queryOpt.StartTime != "" && carbon.Parse(string(postCreatedAt)).Lt(carbon.Parse(queryOpt.StartTime)) {
 		return errors.New("post expiration")
 	}
 
-	if string(postUserId) != twitterUser.TwitterUserID {
-		return errors.New(fmt.Sprintf("invalid link, expected twitter user id: %s got: %s", twitterUser.TwitterUserID, string(postUserId)))
+	if postUserId != twitterUser.TwitterUserID {
+		return errors.New(fmt.Sprintf("invalid link, expected twitter user id: %s got: %s", twitterUser.TwitterUserID, postUserId))
 	}
 
 	ums, err := dao.GetUserMissionByMissionId(ctx, username, mission.ID, queryOpt)
@@ -1068,6 +1035,7 @@ func checkPostTweet(ctx context.Context, mission *model.Mission, username string
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   twitterUser.TwitterUserID,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 
@@ -1080,6 +1048,32 @@ func checkPostTweet(ctx context.Context, mission *model.Mission, username string
 	return nil
 }
 
+// checkTweetContent 根据任务参数校验推文的文本、话题标签及 tag 用户数量
+func checkTweetContent(tweet *fastjson.Value, params *model.MissionParams) error {
+	legacy := tweet.Get("legacy")
+
+	if params.RequiredText != "" && !strings.Contains(string(legacy.GetStringBytes("full_text")), params.RequiredText) {
+		return errors.New("invalid content")
+	}
+
+	hashtags := make(map[string]struct{})
+	for _, h := range legacy.Get("entities").GetArray("hashtags") {
+		hashtags[strings.ToLower(string(h.GetStringBytes("text")))] = struct{}{}
+	}
+
+	for _, tag := range params.RequiredHashtags {
+		if _, ok := hashtags[strings.ToLower(strings.TrimPrefix(tag, "#"))]; !ok {
+			return fmt.Errorf("missing hashtag: %s", tag)
+		}
+	}
+
+	if len(legacy.Get("entities").GetArray("user_mentions")) < params.RequiredMentions {
+		return errors.New("tag users not enough")
+	}
+
+	return nil
+}
+
 func checkJoinTelegram(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
 	telegramOauth, err := dao.GetTelegramOauthByUsername(ctx, username)
 	if err != nil {
@@ -1087,13 +1081,23 @@ func checkJoinTelegram(ctx context.Context, mission *model.Mission, username str
 		return err
 	}
 
-	groupId, _ := strconv.ParseInt(mission.TargetID, 10, 64)
-	_, err = TeleBot.ChatMemberOf(&tele.Chat{ID: groupId}, &tele.Chat{ID: telegramOauth.TelegramUserID})
+	params, err := mission.GetParams()
 	if err != nil {
-		fmt.Println("chat member of: ", err)
+		log.Errorf("GetParams: %v", err)
 		return err
 	}
 
+	groupId, _ := strconv.ParseInt(targetChannel(mission, params), 10, 64)
+	member, err := TeleBot.ChatMemberOf(&tele.Chat{ID: groupId}, &tele.Chat{ID: telegramOauth.TelegramUserID})
+	if err != nil {
+		fmt.Println("chat member of: ", err)
+		return telegramError(err)
+	}
+
+	if member.Role == tele.Left || member.Role == tele.Kicked {
+		return verificationFailed("user not join telegram group")
+	}
+
 	ums, err := dao.GetUserMissionByMissionId(ctx, username, mission.ID, queryOpt)
 	if err != nil {
 		log.Errorf("GetUserMissionByUser: %v", err)
@@ -1107,6 +1111,7 @@ func checkJoinTelegram(ctx context.Context, mission *model.Mission, username str
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   strconv.FormatInt(telegramOauth.TelegramUserID, 10),
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 
@@ -1134,7 +1139,7 @@ func checkJoinDiscord(ctx context.Context, mission *model.Mission, username stri
 	}
 
 	if !existing {
-		return errors.New("user not join discord")
+		return verificationFailed("user not join discord")
 	}
 
 	ums, err := dao.GetUserMissionByMissionId(ctx, username, mission.ID, queryOpt)
@@ -1150,6 +1155,7 @@ func checkJoinDiscord(ctx context.Context, mission *model.Mission, username stri
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   discordUser.DiscordUserID,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 
@@ -1185,6 +1191,7 @@ func checkBindingKOL(ctx context.Context, mission *model.Mission, username strin
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   userInfo.FromKolRefCode,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 
@@ -1197,12 +1204,14 @@ func checkBindingKOL(ctx context.Context, mission *model.Mission, username strin
 	return nil
 }
 
+// checkVisitOfficialWebsite 通过追踪链接访问官网或合作方页面, 独立访问次数达到要求后完成
 func checkVisitOfficialWebsite(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
-	return errors.New("not implement")
+	return checkTrackingVisits(ctx, mission, username, queryOpt)
 }
 
+// checkVisitReferrerPage 通过追踪链接访问用户的邀请页面, 独立访问次数达到要求后完成
 func checkVisitReferrerPage(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
-	return errors.New("not implement")
+	return checkTrackingVisits(ctx, mission, username, queryOpt)
 }
 
 func checkInviteFriendsToDiscord(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
@@ -1212,22 +1221,28 @@ func checkInviteFriendsToDiscord(ctx context.Context, mission *model.Mission, us
 		return err
 	}
 
-	subMission, err := dao.GetSubMissions(ctx, mission.ID)
+	subMission, err := getSubMissions(ctx, mission.ID)
 	if err != nil {
-		log.Errorf("GetSubMissions: %v", err)
+		log.Errorf("getSubMissions: %v", err)
 		return err
 	}
 
-	startOfWeek := carbon.Now().StartOfWeek().String()
-	key := fmt.Sprintf("gm::discord::invitecounter::%s::%s", discordUser.DiscordUserID, startOfWeek)
-	result, err := dao.RedisCache.Get(ctx, key).Result()
+	// 统计当前周期内邀请的人数
+	minScore, maxScore := "-inf", "+inf"
+	if queryOpt.StartTime != "" {
+		minScore = strconv.FormatInt(carbon.Parse(queryOpt.StartTime).Timestamp(), 10)
+	}
+	if queryOpt.EndTime != "" {
+		maxScore = "(" + strconv.FormatInt(carbon.Parse(queryOpt.EndTime).Timestamp(), 10)
+	}
+
+	key := fmt.Sprintf("gm::discord::invites::%s", discordUser.DiscordUserID)
+	count, err := dao.RedisCache.ZCount(ctx, key, minScore, maxScore).Result()
 	if err != nil {
 		log.Errorf("Get invite counter: %v", err)
 		return err
 	}
 
-	count, _ := strconv.ParseInt(result, 10, 64)
-
 	log.Infof("%s invite friends count: %d", discordUser.DiscordUserID, count)
 	fmt.Println("invite friends count:", discordUser.DiscordUserID, count)
 
@@ -1271,10 +1286,18 @@ func checkInviteFriendsToDiscord(ctx context.Context, mission *model.Mission, us
 			Type:         sm.Type,
 			Credit:       sm.Credit,
 			Content:      discordUser.DiscordUserID,
+			Period:       awardPeriod(queryOpt),
 			CreatedAt:    time.Now(),
 		})
 
-		if err != nil {
+		// 子任务奖励已领完时不计入完成数, 继续发放其余子任务
+		if errors.Is(err, dao.ErrMissionSoldOut) {
+			completedCount--
+			continue
+		}
+
+		// 并发校验时子任务可能已发放, 继续发放其余子任务
+		if err != nil && !errors.Is(err, dao.ErrAlreadyAwarded) {
 			log.Errorf("AddUserMission: %v", err)
 			return err
 		}
@@ -1294,7 +1317,13 @@ func checkJoinVolunteerChannel(ctx context.Context, mission *model.Mission, user
 		return err
 	}
 
-	channelId := mission.TargetID
+	params, err := mission.GetParams()
+	if err != nil {
+		log.Errorf("GetParams: %v", err)
+		return err
+	}
+
+	channelId := targetChannel(mission, params)
 
 	permission, err := DCBot.UserChannelPermissions(discordUser.DiscordUserID, channelId)
 	if err != nil {
@@ -1302,7 +1331,7 @@ func checkJoinVolunteerChannel(ctx context.Context, mission *model.Mission, user
 	}
 
 	if permission&discordgo.PermissionViewChannel == 0 {
-		return errors.New("please complete mission first")
+		return verificationFailed("please complete mission first")
 	}
 
 	ums, err := dao.GetUserMissionByMissionId(ctx, username, mission.ID, queryOpt)
@@ -1318,6 +1347,7 @@ func checkJoinVolunteerChannel(ctx context.Context, mission *model.Mission, user
 			Type:      mission.Type,
 			Credit:    mission.Credit,
 			Content:   discordUser.DiscordUserID,
+			Period:    awardPeriod(queryOpt),
 			CreatedAt: time.Now(),
 		})
 
@@ -1345,6 +1375,11 @@ func PostTwitterLinkHandler(c *gin.Context) {
 		return
 	}
 
+	if _, err := parseTweetURL(params.Link); err != nil {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+		return
+	}
+
 	params.Username = username
 	params.CreatedAt = time.Now()
 	err := dao.AddUserTwitterLink(c.Request.Context(), &params)
@@ -1480,30 +1515,51 @@ func GetUserCreditsHandler(c *gin.Context) {
 	}))
 }
 
-// BrowsOfficialWebsite 浏览官网
+// BrowsOfficialWebsite 浏览官网, 创建一次性的浏览会话, 官网定期发送心跳, 浏览时长达到要求后发放积分
 func BrowsOfficialWebsite(c *gin.Context) {
 	claims := jwt.ExtractClaims(c)
 	username := claims[identityKey].(string)
 
-	tnow := time.Now().Unix()
-	value := fmt.Sprintf("%s:%d", username, tnow)
-	code, err := opcrypt.AesEncryptCBC([]byte(value), []byte(config.Cfg.AesKey))
-	if err != nil {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
 		log.Errorf("generate code of brows official website error: %v", err)
 		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 		return
 	}
 
+	code := hex.EncodeToString(buf)
+	if err := dao.CreateBrowseSession(c.Request.Context(), code, username, browseSessionTTL()); err != nil {
+		log.Errorf("CreateBrowseSession: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
 	c.JSON(http.StatusOK, respJSON(JsonObject{
-		"code": code,
-		"uri":  config.Cfg.OfficialWebsiteURI,
+		"code":               code,
+		"uri":                config.Cfg.OfficialWebsiteURI,
+		"heartbeat_interval": browseHeartbeatInterval(),
 	}))
 }
 
-// BrowsOfficialWebsiteCallback 浏览官网回调
-func BrowsOfficialWebsiteCallback(c *gin.Context) {
+// browseHeartbeatInterval 官网发送心跳的间隔, 单位秒
+func browseHeartbeatInterval() int64 {
+	if config.Cfg.BrowsOfficialWebsiteHeartbeat <= 0 {
+		return defaultBrowseHeartbeatInterval
+	}
+	return config.Cfg.BrowsOfficialWebsiteHeartbeat
+}
+
+// browseSessionTTL 超过该时间没有心跳时会话过期
+func browseSessionTTL() time.Duration {
+	return time.Duration(browseHeartbeatInterval()*browseSessionIdleHeartbeats) * time.Second
+}
+
+// BrowsOfficialWebsiteHeartbeat 浏览官网的心跳, 两次心跳的间隔最多计 2 个心跳间隔, 页面不可见时不计时长.
+// 累计时长达到 BrowsOfficialWebsiteTime 后会话被消耗并发放积分, 同一个 code 不能重复使用
+func BrowsOfficialWebsiteHeartbeat(c *gin.Context) {
 	var params = struct {
-		Code string `json:"code"`
+		Code   string `json:"code"`
+		Hidden bool   `json:"hidden"`
 	}{}
 
 	if err := c.BindJSON(&params); err != nil {
@@ -1512,46 +1568,71 @@ func BrowsOfficialWebsiteCallback(c *gin.Context) {
 	}
 
 	code := strings.TrimSpace(params.Code)
-
 	if code == "" {
 		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
 		return
 	}
 
-	tnow := time.Now().Unix()
-
-	value, err := opcrypt.AesDecryptCBC(code, []byte(config.Cfg.AesKey))
-	if err != nil {
-		log.Errorf("get code of brows official website error: %v", err)
-		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+	required := config.Cfg.BrowsOfficialWebsiteTime
+	hb, err := dao.HeartbeatBrowseSession(c.Request.Context(), code, 2*browseHeartbeatInterval(), required, browseSessionTTL(), !params.Hidden)
+	if errors.Is(err, redis.Nil) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
 		return
 	}
 
-	values := strings.Split(string(value), ":")
-	if len(values) < 2 {
-		log.Errorf("split code of brows official website error: %v", err)
-		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
-		return
-	}
-	username := values[0]
-	pnow, _ := strconv.ParseInt(values[1], 10, 64)
-	if pnow == 0 {
-		log.Errorf("time of code error: %v", err)
+	if err != nil {
+		log.Errorf("HeartbeatBrowseSession: %v", err)
 		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 		return
 	}
 
-	// 如果浏览官网到达了规定时间，则发放积分奖励
-	if tnow-pnow >= config.Cfg.BrowsOfficialWebsiteTime {
-		err = completeMission(c.Request.Context(), username, MissionIdBrowsOfficialWebSite)
+	if hb.Completed {
+		errCode, err := completeBrowseSession(c.Request.Context(), hb.Username)
 		if err != nil {
 			log.Errorf("complete brows official website error: %v", err)
 			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
 			return
 		}
+
+		// 积分发放成功或任务已无法完成时才消耗会话, 删除失败时重复的心跳也不会重复发放
+		if err := dao.DeleteBrowseSession(c.Request.Context(), code); err != nil {
+			log.Errorf("DeleteBrowseSession: %v", err)
+		}
+
+		if errCode != 0 {
+			c.JSON(http.StatusOK, respErrorCode(errCode, c))
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, respJSON(nil))
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"dwell":     hb.Dwell,
+		"required":  required,
+		"completed": hb.Completed,
+	}))
+}
+
+// completeBrowseSession 浏览时长达标后发放积分, 任务已结束或奖励已领完时返回对应的错误码, 其他错误可以在下次心跳时重试
+func completeBrowseSession(ctx context.Context, username string) (int, error) {
+	mission, err := getMissionById(ctx, MissionIdBrowsOfficialWebSite)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errorsx.NotFound, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if code := missionStateErrorCode(missionState(mission, time.Now())); code != 0 {
+		return code, nil
+	}
+
+	err = completeMission(ctx, username, mission.ID)
+	if errors.Is(err, dao.ErrMissionSoldOut) {
+		return errorsx.MissionSoldOut, nil
+	}
+
+	return 0, err
 }
 
 // VerifyBrowsOfficialWebsite 验证浏览官网是否完成
@@ -1560,7 +1641,7 @@ func VerifyBrowsOfficialWebsite(c *gin.Context) {
 
 	claims := jwt.ExtractClaims(c)
 	username := claims[identityKey].(string)
-	lang := c.GetHeader("Lang")
+	locale := requestLocale(c)
 
 	complete, err := getMission(c.Request.Context(), username, MissionIdBrowsOfficialWebSite)
 	if err != nil {
@@ -1569,15 +1650,8 @@ func VerifyBrowsOfficialWebsite(c *gin.Context) {
 		return
 	}
 
-	switch strings.ToLower(lang) {
-	case "cn":
-		if !complete {
-			msg = "请先完成任务"
-		}
-	default:
-		if !complete {
-			msg = "Please complete the task first"
-		}
+	if !complete {
+		msg = catalog.Text(locale, msgKeyCompleteMissionFirst)
 	}
 
 	c.JSON(http.StatusOK, respJSON(JsonObject{
@@ -1609,6 +1683,29 @@ func GetInviteLogs(c *gin.Context) {
 	}))
 }
 
+// GetCreditLogs 获取积分流水
+func GetCreditLogs(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	page, _ := c.GetQuery("page")
+	size, _ := c.GetQuery("size")
+	pageInt, _ := strconv.Atoi(page)
+	sizeInt, _ := strconv.Atoi(size)
+
+	out, total, err := dao.GetCreditLedger(c.Request.Context(), username, dao.QueryOption{Page: pageInt, PageSize: sizeInt})
+	if err != nil {
+		log.Errorf("get user credit_ledger error: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"total": total,
+		"list":  out,
+	}))
+}
+
 // GetMissionLogs 获取任务完成记录
 func GetMissionLogs(c *gin.Context) {
 	claims := jwt.ExtractClaims(c)
@@ -1619,7 +1716,13 @@ func GetMissionLogs(c *gin.Context) {
 	pageInt, _ := strconv.Atoi(page)
 	sizeInt, _ := strconv.Atoi(size)
 
-	out, total, err := dao.GetMissionLogs(c.Request.Context(), username, dao.QueryOption{Page: pageInt, PageSize: sizeInt})
+	seasonId, err := querySeasonId(c)
+	if err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	out, total, err := dao.GetMissionLogs(c.Request.Context(), username, dao.QueryOption{Page: pageInt, PageSize: sizeInt, SeasonID: seasonId})
 	if err != nil {
 		log.Errorf("get user mission_log error: %v", err)
 		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
@@ -1635,10 +1738,8 @@ func GetMissionLogs(c *gin.Context) {
 // GetBecomeVolunteerURL 获取跳转链接
 func GetBecomeVolunteerURL(c *gin.Context) {
 	var url string
-	lang := c.GetHeader("Lang")
-
-	switch strings.ToLower(lang) {
-	case "cn":
+	switch requestLocale(c) {
+	case model.LanguageCN:
 		url = config.Cfg.GoogleDoc.CnURI
 	default:
 		url = config.Cfg.GoogleDoc.EnURI
@@ -1661,15 +1762,14 @@ func VerifyBecomeVolunteer(c *gin.Context) {
 	username := claims[identityKey].(string)
 	glog.Println(username)
 
-	lang := c.GetHeader("Lang")
+	locale := requestLocale(c)
+	msg = catalog.Text(locale, msgKeyCompleteMissionFirst)
 
-	// 调用谷歌文档接口进行查询
-	switch strings.ToLower(lang) {
-	case "cn":
-		msg = "请先完成任务"
+	// 调用谷歌文档接口进行查询, 只有中英文两份报表
+	switch locale {
+	case model.LanguageCN:
 		speedID = config.Cfg.GoogleDoc.CnDocID
 	default:
-		msg = "Please complete the task first"
 		speedID = config.Cfg.GoogleDoc.EnDocID
 	}
 
@@ -1700,9 +1800,16 @@ func VerifyBecomeVolunteer(c *gin.Context) {
 func creditsListHandler(c *gin.Context) {
 	page, _ := strconv.ParseInt(c.Query("page"), 10, 64)
 	size, _ := strconv.ParseInt(c.Query("size"), 10, 64)
+	seasonId, err := querySeasonId(c)
+	if err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
 	option := dao.QueryOption{
 		Page:     int(page),
 		PageSize: int(size),
+		SeasonID: seasonId,
 	}
 
 	total, credits, err := dao.GetCreditsList(c.Request.Context(), option)
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -20,9 +21,13 @@ var (
 )
 
 func completeMission(ctx context.Context, username string, missionID int64) error {
-	mission, err := dao.GetMissionById(ctx, missionID)
+	mission, err := getActiveMission(ctx, missionID)
 	if err != nil {
-		log.Errorf("GetMissionById: %v", err)
+		log.Errorf("getActiveMission: %v", err)
+		return err
+	}
+
+	if err := checkMissionAvailable(mission); err != nil {
 		return err
 	}
 
@@ -33,7 +38,7 @@ func completeMission(ctx context.Context, username string, missionID int64) erro
 	}
 
 	if len(ums) == 0 {
-		return dao.AddUserMissionAndInviteLog(ctx, &model.UserMission{
+		err = dao.AddUserMissionAndInviteLog(ctx, &model.UserMission{
 			Username:  username,
 			MissionID: mission.ID,
 			Type:      mission.Type,
@@ -41,15 +46,18 @@ func completeMission(ctx context.Context, username string, missionID int64) erro
 			Content:   username,
 			CreatedAt: time.Now(),
 		})
+		if err != nil && !errors.Is(err, dao.ErrAlreadyAwarded) {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func getMission(ctx context.Context, username string, missionID int64) (bool, error) {
-	mission, err := dao.GetMissionById(ctx, missionID)
+	mission, err := getActiveMission(ctx, missionID)
 	if err != nil {
-		log.Errorf("GetMissionById: %v", err)
+		log.Errorf("getActiveMission: %v", err)
 		return false, err
 	}
 
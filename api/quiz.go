@@ -0,0 +1,534 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+const (
+	minQuizOptions = 2
+	maxQuizOptions = 10
+)
+
+// RespQuizQuestion 返回给用户的题目, 不包含正确答案
+type RespQuizQuestion struct {
+	ID       int64             `json:"id"`
+	Question string            `json:"question"`
+	Multiple bool              `json:"multiple"`
+	Options  []*RespQuizOption `json:"options"`
+}
+
+type RespQuizOption struct {
+	Key  string `json:"key"`
+	Text string `json:"text"`
+}
+
+// SubmitQuizRequest 提交答案, answers 的键为题目id, 值为选择的选项 key
+type SubmitQuizRequest struct {
+	MissionID int64              `json:"mission_id"`
+	Answers   map[int64][]string `json:"answers"`
+}
+
+func quizQuestionKey(id int64) string {
+	return fmt.Sprintf("quiz.%d.question", id)
+}
+
+func quizOptionKey(id int64, key string) string {
+	return fmt.Sprintf("quiz.%d.option.%s", id, key)
+}
+
+// quizPassingScore 及格分数, 未配置时需要全部答对
+func quizPassingScore(params *model.MissionParams) int64 {
+	if params.QuizPassingScore <= 0 {
+		return 100
+	}
+	return int64(params.QuizPassingScore)
+}
+
+// requireQuizMaxAttempts 答题任务必须限制答题次数, 否则可以根据每次返回的答对题数逐题猜出答案
+func requireQuizMaxAttempts(mission *model.Mission, params *model.MissionParams) error {
+	if params.QuizMaxAttempts <= 0 {
+		return errors.New("quiz_max_attempts must be positive")
+	}
+	return nil
+}
+
+// localizeQuizQuestion 返回对应语言的题目, 选项的顺序与配置一致
+func localizeQuizQuestion(q *model.QuizQuestion, locale string) (*RespQuizQuestion, error) {
+	options, err := q.GetOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RespQuizQuestion{
+		ID:       q.ID,
+		Question: localize(quizQuestionKey(q.ID), locale, q.Question, q.QuestionCn),
+		Multiple: len(q.AnswerKeys()) > 1,
+	}
+
+	for _, opt := range options {
+		out.Options = append(out.Options, &RespQuizOption{
+			Key:  opt.Key,
+			Text: localize(quizOptionKey(q.ID, opt.Key), locale, opt.Text, opt.TextCn),
+		})
+	}
+
+	return out, nil
+}
+
+// shuffleQuiz 打乱题目及选项的顺序
+func shuffleQuiz(questions []*RespQuizQuestion) {
+	rand.Shuffle(len(questions), func(i, j int) {
+		questions[i], questions[j] = questions[j], questions[i]
+	})
+
+	for _, q := range questions {
+		rand.Shuffle(len(q.Options), func(i, j int) {
+			q.Options[i], q.Options[j] = q.Options[j], q.Options[i]
+		})
+	}
+}
+
+// gradeQuiz 计算得分, 选择的选项与正确选项完全一致时该题得分, 返回百分制的得分及答对的题数
+func gradeQuiz(questions []*model.QuizQuestion, answers map[int64][]string) (int64, int) {
+	if len(questions) == 0 {
+		return 0, 0
+	}
+
+	var correct int
+	for _, q := range questions {
+		if sameKeys(q.AnswerKeys(), answers[q.ID]) {
+			correct++
+		}
+	}
+
+	return int64(correct * 100 / len(questions)), correct
+}
+
+func sameKeys(expected, actual []string) bool {
+	set := make(map[string]struct{}, len(actual))
+	for _, key := range actual {
+		set[strings.TrimSpace(key)] = struct{}{}
+	}
+
+	if len(expected) == 0 || len(set) != len(expected) {
+		return false
+	}
+
+	for _, key := range expected {
+		if _, ok := set[key]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getQuizMission 获取答题任务及其校验参数, 不是答题任务时返回 sql.ErrNoRows
+func getQuizMission(ctx context.Context, missionId int64) (*model.Mission, *model.MissionParams, error) {
+	mission, err := getMissionById(ctx, missionId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mission.Verifier != VerifierQuiz {
+		return nil, nil, sql.ErrNoRows
+	}
+
+	params, err := mission.GetParams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mission, params, nil
+}
+
+// GetQuizHandler 获取答题任务的题目及用户在当前周期的答题情况, 配置了 quiz_shuffle 时每次返回的顺序不同
+func GetQuizHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	missionId, _ := strconv.ParseInt(c.Query("mission_id"), 10, 64)
+	mission, params, err := getQuizMission(c.Request.Context(), missionId)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("getQuizMission: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	now := time.Now()
+	if code := missionStateErrorCode(missionState(mission, now)); code != 0 {
+		c.JSON(http.StatusOK, respErrorCode(code, c))
+		return
+	}
+
+	window, err := missionWindow(mission, now)
+	if err != nil {
+		log.Errorf("missionWindow: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	questions, err := dao.GetQuizQuestions(c.Request.Context(), mission.ID)
+	if err != nil {
+		log.Errorf("GetQuizQuestions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	attempts, err := dao.GetQuizAttempts(c.Request.Context(), username, mission.ID, awardPeriod(windowQueryOption(window)))
+	if err != nil {
+		log.Errorf("GetQuizAttempts: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	locale := requestLocale(c)
+	out := make([]*RespQuizQuestion, 0, len(questions))
+	for _, q := range questions {
+		rq, err := localizeQuizQuestion(q, locale)
+		if err != nil {
+			log.Errorf("localizeQuizQuestion %d: %v", q.ID, err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+		out = append(out, rq)
+	}
+
+	if params.QuizShuffle {
+		shuffleQuiz(out)
+	}
+
+	var passed bool
+	for _, a := range attempts {
+		passed = passed || a.Passed
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"mission_id":    mission.ID,
+		"title":         missionTitle(dao.MissionTable, mission, locale),
+		"questions":     out,
+		"passing_score": quizPassingScore(params),
+		"max_attempts":  params.QuizMaxAttempts,
+		"attempts":      len(attempts),
+		"passed":        passed,
+	}))
+}
+
+// SubmitQuizHandler 提交答案, 在服务端评分, 通过后按正常流程发放积分. 只返回得分, 不返回正确答案
+func SubmitQuizHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	var req SubmitQuizRequest
+	if err := c.BindJSON(&req); err != nil || req.MissionID <= 0 {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	mission, params, err := getQuizMission(c.Request.Context(), req.MissionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("getQuizMission: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	queryOpt, code := checkUserMissionSubmittable(c.Request.Context(), mission, username, time.Now())
+	if code != 0 {
+		c.JSON(http.StatusOK, respErrorCode(code, c))
+		return
+	}
+
+	questions, err := dao.GetQuizQuestions(c.Request.Context(), mission.ID)
+	if err != nil {
+		log.Errorf("GetQuizQuestions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	if len(questions) == 0 {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	// 只保存任务中题目的答案
+	answers := make(map[int64][]string, len(questions))
+	for _, q := range questions {
+		if keys, ok := req.Answers[q.ID]; ok {
+			if len(keys) > maxQuizOptions {
+				keys = keys[:maxQuizOptions]
+			}
+			answers[q.ID] = keys
+		}
+	}
+
+	content, err := json.Marshal(answers)
+	if err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	score, correct := gradeQuiz(questions, answers)
+	attempt := &model.QuizAttempt{
+		Username:  username,
+		MissionID: mission.ID,
+		Period:    awardPeriod(queryOpt),
+		Score:     score,
+		Passed:    score >= quizPassingScore(params),
+		Answers:   content,
+	}
+
+	err = dao.AddQuizAttempt(c.Request.Context(), attempt, params.QuizMaxAttempts)
+	if errors.Is(err, dao.ErrQuizPassed) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.MissionComplete, c))
+		return
+	}
+
+	if errors.Is(err, dao.ErrQuizAttemptsExceeded) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.QuizAttemptsExceeded, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("AddQuizAttempt: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	if attempt.Passed {
+		err = awardQuizAttempt(c.Request.Context(), mission, attempt)
+		if errors.Is(err, dao.ErrAlreadyAwarded) {
+			err = nil
+		}
+
+		if errors.Is(err, dao.ErrMissionSoldOut) {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.MissionSoldOut, c))
+			return
+		}
+
+		if err != nil {
+			log.Errorf("awardQuizAttempt: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"score":   score,
+		"correct": correct,
+		"total":   len(questions),
+		"passed":  attempt.Passed,
+	}))
+}
+
+// checkQuiz 答题任务的校验器, 当前周期内通过答题后发放积分
+func checkQuiz(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	attempts, err := dao.GetQuizAttempts(ctx, username, mission.ID, awardPeriod(queryOpt))
+	if err != nil {
+		return err
+	}
+
+	for _, a := range attempts {
+		if a.Passed {
+			return awardQuizAttempt(ctx, mission, a)
+		}
+	}
+
+	return errors.New("please pass the quiz first")
+}
+
+// awardQuizAttempt 为通过的答题发放积分, 同一周期只发放一次
+func awardQuizAttempt(ctx context.Context, mission *model.Mission, attempt *model.QuizAttempt) error {
+	return dao.AddUserMissionAndInviteLog(ctx, &model.UserMission{
+		Username:  attempt.Username,
+		MissionID: mission.ID,
+		Type:      mission.Type,
+		Credit:    mission.Credit,
+		Content:   strconv.FormatInt(attempt.ID, 10),
+		Period:    attempt.Period,
+		CreatedAt: time.Now(),
+	})
+}
+
+// QuizQuestionRequest 新增或修改题目, id 为 0 时新增
+type QuizQuestionRequest struct {
+	ID         int64               `json:"id"`
+	MissionID  int64               `json:"mission_id"`
+	Question   string              `json:"question"`
+	QuestionCn string              `json:"question_cn"`
+	Options    []*model.QuizOption `json:"options"`
+	Answer     []string            `json:"answer"`
+	SortID     int32               `json:"sort_id"`
+}
+
+// validate 校验题目, 选项的 key 不能重复, 正确答案必须是选项之一
+func (r *QuizQuestionRequest) validate() error {
+	if r.MissionID <= 0 {
+		return errors.New("mission_id is required")
+	}
+
+	r.Question = strings.TrimSpace(r.Question)
+	r.QuestionCn = strings.TrimSpace(r.QuestionCn)
+	if r.Question == "" {
+		return errors.New("question is required")
+	}
+
+	if len(r.Options) < minQuizOptions || len(r.Options) > maxQuizOptions {
+		return fmt.Errorf("options must have %d to %d items", minQuizOptions, maxQuizOptions)
+	}
+
+	keys := make(map[string]struct{}, len(r.Options))
+	for _, opt := range r.Options {
+		if opt == nil {
+			return errors.New("options contains an empty option")
+		}
+
+		opt.Key = strings.TrimSpace(opt.Key)
+		opt.Text = strings.TrimSpace(opt.Text)
+		opt.TextCn = strings.TrimSpace(opt.TextCn)
+		if opt.Key == "" || strings.Contains(opt.Key, ",") || opt.Text == "" {
+			return errors.New("option key and text are required, key must not contain commas")
+		}
+
+		if _, ok := keys[opt.Key]; ok {
+			return fmt.Errorf("duplicate option key: %s", opt.Key)
+		}
+		keys[opt.Key] = struct{}{}
+	}
+
+	answer := make(map[string]struct{}, len(r.Answer))
+	for _, key := range r.Answer {
+		key = strings.TrimSpace(key)
+		if _, ok := keys[key]; !ok {
+			return fmt.Errorf("answer %q is not an option", key)
+		}
+		answer[key] = struct{}{}
+	}
+
+	if len(answer) == 0 {
+		return errors.New("answer is required")
+	}
+
+	r.Answer = r.Answer[:0]
+	for key := range answer {
+		r.Answer = append(r.Answer, key)
+	}
+	sort.Strings(r.Answer)
+
+	return nil
+}
+
+// AdminQuizQuestionsHandler 获取答题任务的题目, 包含正确答案
+func AdminQuizQuestionsHandler(c *gin.Context) {
+	missionId, _ := strconv.ParseInt(c.Query("mission_id"), 10, 64)
+	if missionId <= 0 {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	out, err := dao.GetQuizQuestions(c.Request.Context(), missionId)
+	if err != nil {
+		log.Errorf("GetQuizQuestions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(out))
+}
+
+// AdminSaveQuizQuestionHandler 新增或修改题目, 题目及选项的其他语言通过文案表配置
+func AdminSaveQuizQuestionHandler(c *gin.Context) {
+	var req QuizQuestionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+		return
+	}
+
+	if _, _, err := getQuizMission(c.Request.Context(), req.MissionID); err != nil {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, errors.New("mission is not a quiz"), c))
+		return
+	}
+
+	options, err := json.Marshal(req.Options)
+	if err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	q := &model.QuizQuestion{
+		ID:         req.ID,
+		MissionID:  req.MissionID,
+		Question:   req.Question,
+		QuestionCn: req.QuestionCn,
+		Options:    options,
+		Answer:     strings.Join(req.Answer, ","),
+		SortID:     req.SortID,
+	}
+
+	err = dao.SaveQuizQuestion(c.Request.Context(), q)
+	recordOperation(c, "save quiz question", BusinessTypeUpdate, req, q, err)
+	if errors.Is(err, dao.ErrNoRow) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("SaveQuizQuestion: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(q))
+}
+
+// AdminDeleteQuizQuestionHandler 删除题目
+func AdminDeleteQuizQuestionHandler(c *gin.Context) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := c.BindJSON(&req); err != nil || req.ID <= 0 {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	err := dao.DeleteQuizQuestion(c.Request.Context(), req.ID)
+	recordOperation(c, "delete quiz question", BusinessTypeDelete, req, nil, err)
+	if err != nil {
+		log.Errorf("DeleteQuizQuestion: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(nil))
+}
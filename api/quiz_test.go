@@ -0,0 +1,86 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQuizMission(t *testing.T) {
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierQuiz, Type: MissionTypeBasic}))
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierQuiz, Type: MissionTypeBasic, Params: []byte(`{"quiz_max_attempts":0}`)}))
+	require.NoError(t, ValidateMission(&model.Mission{Verifier: VerifierQuiz, Type: MissionTypeBasic, Params: []byte(`{"quiz_max_attempts":3}`)}))
+}
+
+func TestGradeQuiz(t *testing.T) {
+	questions := []*model.QuizQuestion{
+		{ID: 1, Answer: "A"},
+		{ID: 2, Answer: "B,C"},
+		{ID: 3, Answer: "D"},
+	}
+
+	score, correct := gradeQuiz(questions, map[int64][]string{1: {"A"}, 2: {"C", "B"}, 3: {"D"}})
+	require.Equal(t, int64(100), score)
+	require.Equal(t, 3, correct)
+
+	// multiple choice must match exactly, unanswered questions count as wrong
+	score, correct = gradeQuiz(questions, map[int64][]string{1: {"A"}, 2: {"B"}})
+	require.Equal(t, int64(33), score)
+	require.Equal(t, 1, correct)
+
+	score, _ = gradeQuiz(questions, map[int64][]string{2: {"B", "C", "D"}, 3: {"D", "D"}})
+	require.Equal(t, int64(33), score)
+
+	score, correct = gradeQuiz(nil, nil)
+	require.Zero(t, score)
+	require.Zero(t, correct)
+
+	require.Equal(t, int64(100), quizPassingScore(&model.MissionParams{}))
+	require.Equal(t, int64(60), quizPassingScore(&model.MissionParams{QuizPassingScore: 60}))
+}
+
+func TestQuizQuestionRequest(t *testing.T) {
+	options := func() []*model.QuizOption {
+		return []*model.QuizOption{{Key: "A", Text: "Edge computing"}, {Key: "B", Text: "Storage"}, {Key: "C", Text: "Both"}}
+	}
+
+	req := &QuizQuestionRequest{MissionID: 1, Question: " What is Titan? ", Options: options(), Answer: []string{"C", " A", "A"}}
+	require.NoError(t, req.validate())
+	require.Equal(t, "What is Titan?", req.Question)
+	require.Equal(t, []string{"A", "C"}, req.Answer)
+
+	require.Error(t, (&QuizQuestionRequest{Question: "q", Options: options(), Answer: []string{"A"}}).validate())
+	require.Error(t, (&QuizQuestionRequest{MissionID: 1, Options: options(), Answer: []string{"A"}}).validate())
+	require.Error(t, (&QuizQuestionRequest{MissionID: 1, Question: "q", Options: options()[:1], Answer: []string{"A"}}).validate())
+	require.Error(t, (&QuizQuestionRequest{MissionID: 1, Question: "q", Options: options()}).validate())
+	require.Error(t, (&QuizQuestionRequest{MissionID: 1, Question: "q", Options: options(), Answer: []string{"D"}}).validate())
+
+	dup := append(options(), &model.QuizOption{Key: "A", Text: "again"})
+	require.Error(t, (&QuizQuestionRequest{MissionID: 1, Question: "q", Options: dup, Answer: []string{"A"}}).validate())
+}
+
+func TestLocalizeQuizQuestion(t *testing.T) {
+	q := &model.QuizQuestion{
+		ID:         1,
+		Question:   "What is Titan?",
+		QuestionCn: "Titan 是什么?",
+		Options:    []byte(`[{"key":"A","text":"Edge computing","text_cn":"边缘计算"},{"key":"B","text":"Storage"}]`),
+		Answer:     "A",
+	}
+
+	out, err := localizeQuizQuestion(q, model.LanguageCN)
+	require.NoError(t, err)
+	require.Equal(t, "Titan 是什么?", out.Question)
+	require.False(t, out.Multiple)
+	require.Equal(t, "边缘计算", out.Options[0].Text)
+	require.Equal(t, "Storage", out.Options[1].Text)
+
+	out, err = localizeQuizQuestion(q, model.LanguageEN)
+	require.NoError(t, err)
+	require.Equal(t, "What is Titan?", out.Question)
+
+	questions := []*RespQuizQuestion{out}
+	shuffleQuiz(questions)
+	require.Len(t, questions[0].Options, 2)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/gnasnik/titan-quest/pkg/recurrence"
+	"github.com/golang-module/carbon/v2"
+)
+
+// missionRecurrence 获取任务的重复规则, 未配置时按任务类型推断
+func missionRecurrence(mission *model.Mission) (recurrence.Rule, error) {
+	rule := mission.Recurrence
+	if rule == "" {
+		switch mission.Type {
+		case MissionTypeDaily:
+			rule = recurrence.Daily
+		case MissionTypeWeekly:
+			rule = recurrence.Weekly
+		}
+	}
+
+	return recurrence.Parse(rule)
+}
+
+// missionWindow 获取任务在 t 时刻所处的周期, 周期按计算连续天数的时区划分, 保证每日任务的周期与连续天数的日期一致
+func missionWindow(mission *model.Mission, t time.Time) (recurrence.Window, error) {
+	rule, err := missionRecurrence(mission)
+	if err != nil {
+		return recurrence.Window{}, err
+	}
+
+	return rule.Window(t.In(dao.StreakLocation())), nil
+}
+
+// windowQueryOption 将周期转换为查询完成记录的时间范围, 数据库中的时间为服务器时区
+func windowQueryOption(w recurrence.Window) dao.QueryOption {
+	var opt dao.QueryOption
+	if !w.Start.IsZero() {
+		opt.StartTime = w.Start.In(time.Local).Format(carbon.DateTimeLayout)
+	}
+	if !w.End.IsZero() {
+		opt.EndTime = w.End.In(time.Local).Format(carbon.DateTimeLayout)
+	}
+	return opt
+}
+
+// awardPeriod 任务完成记录的周期标识, 与 user_mission 的唯一索引一起保证同一周期内只发放一次积分.
+// 按推文校验的任务在更换推文后可以再次完成, 因此周期标识包含推文链接
+func awardPeriod(opt dao.QueryOption) string {
+	if opt.Content == "" {
+		return opt.StartTime
+	}
+	return opt.StartTime + "#" + opt.Content
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/gnasnik/titan-quest/pkg/streak"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAwardPeriod(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.Local)
+
+	daily := &model.Mission{Type: MissionTypeDaily}
+	w, err := missionWindow(daily, now)
+	require.NoError(t, err)
+	require.Equal(t, "2024-05-15 00:00:00", awardPeriod(windowQueryOption(w)))
+
+	// 同一周期内任意时刻的周期标识相同
+	w2, err := missionWindow(daily, now.Add(11*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, awardPeriod(windowQueryOption(w)), awardPeriod(windowQueryOption(w2)))
+
+	basic := &model.Mission{Type: MissionTypeBasic}
+	w, err = missionWindow(basic, now)
+	require.NoError(t, err)
+	require.Equal(t, "", awardPeriod(windowQueryOption(w)))
+
+	opt := windowQueryOption(w)
+	opt.Content = "https://x.com/titannet_dao/status/1"
+	require.Equal(t, "#https://x.com/titannet_dao/status/1", awardPeriod(opt))
+}
+
+func TestMissionWindowTimezone(t *testing.T) {
+	old := config.Cfg.Streak
+	defer func() { config.Cfg.Streak = old }()
+	config.Cfg.Streak = config.StreakConfig{Timezone: "Asia/Shanghai"}
+
+	// UTC 20 点已是上海时间的第二天, 周期与连续天数的日期一致
+	now := time.Date(2024, 5, 15, 20, 0, 0, 0, time.UTC)
+	w, err := missionWindow(&model.Mission{Type: MissionTypeDaily}, now)
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 15, 16, 0, 0, 0, time.UTC)
+	require.True(t, w.Start.Equal(start))
+	require.Equal(t, "2024-05-16", streak.Day(w.Start, dao.StreakLocation()))
+	require.Equal(t, streak.Day(now, dao.StreakLocation()), streak.Day(w.Start, dao.StreakLocation()))
+
+	// 查询条件按服务器时区格式化
+	require.Equal(t, start.In(time.Local).Format("2006-01-02 15:04:05"), windowQueryOption(w).StartTime)
+}
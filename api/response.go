@@ -3,9 +3,7 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 	err "github.com/gnasnik/titan-quest/core/errors"
-	"github.com/gnasnik/titan-quest/core/generated/model"
 	"github.com/pkg/errors"
-	"strings"
 )
 
 type JsonObject map[string]interface{}
@@ -32,24 +30,31 @@ func respError(e error) gin.H {
 }
 
 func respErrorCode(code int, c *gin.Context) gin.H {
-	lang := c.GetHeader("Lang")
-
-	var msg string
-
-	messages := strings.Split(err.ErrMap[code], ":")
-	if len(messages) == 0 {
-		msg = err.ErrMap[code]
-	} else {
-		if lang == model.LanguageCN {
-			msg = messages[1]
-		} else {
-			msg = messages[0]
-		}
+	return gin.H{
+		"code": -1,
+		"err":  code,
+		"msg":  errorMessage(code, requestLocale(c)),
 	}
+}
 
+// respErrorMessage 在错误码的提示信息后附加具体的错误原因
+func respErrorMessage(code int, e error, c *gin.Context) gin.H {
 	return gin.H{
 		"code": -1,
 		"err":  code,
-		"msg":  msg,
+		"msg":  errorMessage(code, requestLocale(c)) + ": " + e.Error(),
 	}
 }
+
+// errorMessage 获取错误码对应语言的提示信息
+func errorMessage(code int, locale string) string {
+	if msg, ok := catalog.Lookup(locale, errorMessageKey(code)); ok {
+		return msg
+	}
+	return catalog.Text(locale, errorMessageKey(err.Unknown))
+}
+
+// newErrorCode 生成请求语言的错误
+func newErrorCode(code int, c *gin.Context) err.GenericError {
+	return err.GenericError{Code: code, Err: errors.New(errorMessage(code, requestLocale(c)))}
+}
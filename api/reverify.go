@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+const (
+	defaultReverifyInterval        = 60  // 分钟
+	defaultReverifyRecheckInterval = 168 // 小时
+	defaultReverifySampleSize      = 200
+	defaultReverifyUToolSampleSize = 20
+	defaultReverifyGracePeriod     = 48 // 小时
+
+	// 宽限期后仍未通过校验的处理方式
+	ReverifyActionRevoke = "revoke"
+	ReverifyActionFlag   = "flag"
+
+	reverifyOperator = "reverify"
+)
+
+// reverifiableVerifiers 可以撤销的任务, 用户完成后可能取消关注或退出群组
+var reverifiableVerifiers = []string{
+	VerifierFollowTwitter,
+	VerifierJoinDiscord,
+	VerifierJoinDiscordChannel,
+	VerifierJoinTelegram,
+}
+
+// utoolVerifiers 需要调用 UTool 接口校验的任务, 单独限制每轮的校验数量
+var utoolVerifiers = map[string]bool{
+	VerifierFollowTwitter: true,
+}
+
+func reverifyConfig() config.ReverifyConfig {
+	cfg := config.Cfg.Reverify
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultReverifyInterval
+	}
+	if cfg.RecheckInterval <= 0 {
+		cfg.RecheckInterval = defaultReverifyRecheckInterval
+	}
+	if cfg.SampleSize <= 0 {
+		cfg.SampleSize = defaultReverifySampleSize
+	}
+	if cfg.UToolSampleSize <= 0 {
+		cfg.UToolSampleSize = defaultReverifyUToolSampleSize
+	}
+	if cfg.UToolSampleSize > cfg.SampleSize {
+		cfg.UToolSampleSize = cfg.SampleSize
+	}
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = defaultReverifyGracePeriod
+	}
+	if cfg.Action != ReverifyActionRevoke {
+		cfg.Action = ReverifyActionFlag
+	}
+	return cfg
+}
+
+// splitVerifiers 将可重新校验的任务按是否消耗 UTool key 分组
+func splitVerifiers(names []string) (utool []string, others []string) {
+	for _, name := range names {
+		if utoolVerifiers[name] {
+			utool = append(utool, name)
+		} else {
+			others = append(others, name)
+		}
+	}
+	return
+}
+
+// StartReverifyJob 启动已完成任务的定期重新校验
+func StartReverifyJob(ctx context.Context, cfg *config.Config) {
+	if !cfg.Reverify.Enable {
+		return
+	}
+
+	go func() {
+		interval := time.Duration(reverifyConfig().Interval) * time.Minute
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				reverifyUserMissions(ctx, now)
+			}
+		}
+	}()
+}
+
+// reverifyUserMissions 抽样校验一轮最久未校验的完成记录
+func reverifyUserMissions(ctx context.Context, now time.Time) {
+	cfg := reverifyConfig()
+	checkedBefore := now.Add(-time.Duration(cfg.RecheckInterval) * time.Hour)
+	failedBefore := now.Add(-time.Duration(cfg.GracePeriod) * time.Hour)
+
+	utool, others := splitVerifiers(reverifiableVerifiers)
+
+	ums, err := dao.GetUserMissionsForRecheck(ctx, MissionTypeBasic, MissionStatusActive, utool, checkedBefore, failedBefore, cfg.UToolSampleSize)
+	if err != nil {
+		log.Errorf("GetUserMissionsForRecheck: %v", err)
+		return
+	}
+
+	rest, err := dao.GetUserMissionsForRecheck(ctx, MissionTypeBasic, MissionStatusActive, others, checkedBefore, failedBefore, cfg.SampleSize-len(ums))
+	if err != nil {
+		log.Errorf("GetUserMissionsForRecheck: %v", err)
+		return
+	}
+
+	missions := make(map[int64]*model.Mission)
+	for _, um := range append(ums, rest...) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		mission, ok := missions[um.MissionID]
+		if !ok {
+			mission, err = getMissionById(ctx, um.MissionID)
+			if err != nil {
+				log.Errorf("getMissionById: %v", err)
+				continue
+			}
+			missions[um.MissionID] = mission
+		}
+
+		if err := reverifyUserMission(ctx, cfg, mission, um); err != nil {
+			log.Errorf("reverify user mission %d: %v", um.ID, err)
+		}
+	}
+}
+
+// reverifyUserMission 重新校验一条完成记录, 首次失败时通知用户, 宽限期后仍未通过时撤销积分或标记等待人工处理
+func reverifyUserMission(ctx context.Context, cfg config.ReverifyConfig, mission *model.Mission, um *model.UserMission) error {
+	recheck, err := dao.GetUserMissionRecheck(ctx, um.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		recheck = &model.UserMissionRecheck{
+			UserMissionID: um.ID,
+			Username:      um.Username,
+			MissionID:     um.MissionID,
+		}
+	} else if err != nil {
+		return err
+	}
+
+	verifier, ok := GetVerifier(mission)
+	if !ok {
+		return nil
+	}
+
+	vctx, cancel := context.WithTimeout(ctx, verifyJobTimeout)
+	err = verifier.Verify(vctx, mission, um.Username, dao.QueryOption{})
+	cancel()
+
+	// 只有确认用户未完成任务时才记为失败, 接口、数据库等错误不影响校验结果, 下一轮再试
+	if err != nil && !errors.Is(err, errVerificationFailed) {
+		log.Warnf("reverify user mission %d: %v", um.ID, err)
+		return nil
+	}
+
+	now := time.Now()
+	recheck.CheckedAt = now
+
+	switch {
+	case err == nil:
+		recheck.Status = dao.RecheckStatusPassed
+		recheck.Reason = ""
+		recheck.FailedAt = time.Time{}
+		return dao.SaveUserMissionRecheck(ctx, recheck)
+	case recheck.Status != dao.RecheckStatusFailing:
+		recheck.Status = dao.RecheckStatusFailing
+		recheck.Reason = err.Error()
+		recheck.FailedAt = now
+		if err := dao.SaveUserMissionRecheck(ctx, recheck); err != nil {
+			return err
+		}
+
+		deadline := now.Add(time.Duration(cfg.GracePeriod) * time.Hour)
+		return notifyUser(ctx, um.Username, NotificationMissionRecheckFailed, mission, deadline)
+	}
+
+	recheck.Reason = err.Error()
+	if cfg.Action != ReverifyActionRevoke {
+		recheck.Status = dao.RecheckStatusFlagged
+		return dao.SaveUserMissionRecheck(ctx, recheck)
+	}
+
+	if err := dao.RevokeUserMission(ctx, um, "reverify failed: "+recheck.Reason, reverifyOperator); err != nil {
+		return err
+	}
+
+	recheck.Status = dao.RecheckStatusRevoked
+	if err := dao.SaveUserMissionRecheck(ctx, recheck); err != nil {
+		return err
+	}
+
+	return notifyUser(ctx, um.Username, NotificationMissionRevoked, mission, time.Time{})
+}
+
+func notifyUser(ctx context.Context, username, notificationType string, mission *model.Mission, deadline time.Time) error {
+	params := notificationParams{
+		MissionID: mission.ID,
+		Title:     mission.Title,
+		TitleCn:   mission.TitleCn,
+		Credit:    mission.Credit,
+	}
+	if !deadline.IsZero() {
+		params.Deadline = deadline.Unix()
+	}
+
+	return addNotification(ctx, username, notificationType, params)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverifyConfig(t *testing.T) {
+	old := config.Cfg.Reverify
+	defer func() { config.Cfg.Reverify = old }()
+
+	config.Cfg.Reverify = config.ReverifyConfig{SampleSize: 10, UToolSampleSize: 50, Action: "unknown"}
+	cfg := reverifyConfig()
+	require.Equal(t, 10, cfg.UToolSampleSize)
+	require.Equal(t, ReverifyActionFlag, cfg.Action)
+	require.EqualValues(t, defaultReverifyGracePeriod, cfg.GracePeriod)
+
+	utool, others := splitVerifiers(reverifiableVerifiers)
+	require.Equal(t, []string{VerifierFollowTwitter}, utool)
+	require.NotContains(t, others, VerifierFollowTwitter)
+}
+
+func TestNotificationMessage(t *testing.T) {
+	n := &model.UserNotification{
+		Type:   NotificationMissionRevoked,
+		Params: []byte(`{"mission_id":1002,"title":"Follow","title_cn":"关注","credit":5}`),
+	}
+
+	require.Equal(t, `The 5 credits of "Follow" have been revoked because the mission is no longer completed.`, notificationMessage(n, ""))
+	require.Equal(t, "任务「关注」已不满足完成条件, 获得的 5 积分已撤销。", notificationMessage(n, model.LanguageCN))
+}
+
+func TestVerificationFailed(t *testing.T) {
+	err := verificationFailed("user unfollow")
+	require.Equal(t, "user unfollow", err.Error())
+	require.ErrorIs(t, err, errVerificationFailed)
+	require.ErrorIs(t, fmt.Errorf("check: %w", err), errVerificationFailed)
+	require.NotErrorIs(t, errors.New("tweet not found"), errVerificationFailed)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/gnasnik/titan-quest/config"
 	"github.com/gnasnik/titan-quest/core/bot/discord"
+	"github.com/gnasnik/titan-quest/core/dao"
 
 	"github.com/gin-gonic/gin"
 	logging "github.com/ipfs/go-log/v2"
@@ -75,6 +76,9 @@ func InitCaptcha() {
 func ServerAPI(cfg *config.Config) {
 	gin.SetMode(cfg.Mode)
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("SetTrustedProxies: %v", err)
+	}
 	// r.Use(Cors())
 	r.Use(RequestLoggerMiddleware())
 
@@ -98,10 +102,13 @@ func ServerAPI(cfg *config.Config) {
 
 	apiV1.GET("/twitter/callback", TwitterCallBackHandler)
 	apiV1.GET("/discord/callback", DiscordCallBackHandler)
-	apiV1.POST("/brows_official_website/callback", BrowsOfficialWebsiteCallback)
+	apiV1.GET("/github/callback", GithubCallBackHandler)
+	apiV1.POST("/brows_official_website/heartbeat", BrowsOfficialWebsiteHeartbeat)
+	apiV1.GET("/track/:token", TrackingVisitHandler)
 
 	apiV1.GET("/kol_referral_list", GetUserCreditsHandler)
 	apiV1.GET("/credits/list", creditsListHandler)
+	apiV1.GET("/seasons", GetSeasonsHandler)
 
 	user := apiV1.Group("/user")
 	user.GET("/login_before", GetNonceStringHandler)
@@ -115,15 +122,24 @@ func ServerAPI(cfg *config.Config) {
 	user.GET("/info", GetUserInfoHandler)
 	user.GET("/twitter/auth", TwitterOAuthHandler)
 	user.GET("/discord/auth", DiscordOAuthHandler)
+	user.GET("/github/auth", GithubOAuthHandler)
 	user.POST("/telegram/bind", TelegramBindHandler)
 	user.POST("/wallet/bind", BindWalletHandler)
+	user.GET("/notifications", GetNotificationsHandler)
+	user.POST("/notifications/read", ReadNotificationsHandler)
 
 	quest := apiV1.Group("/quest")
-	quest.GET("/query_missions", QueryMissionHandler)
+	quest.GET("/query_missions", OptionalAuthMiddleware(authMiddleware), QueryMissionHandler)
 	quest.Use(authMiddleware.MiddlewareFunc())
 	quest.GET("/query_user_credits", QueryUserCreditsHandler)
 	quest.GET("/check", CheckQuestHandler)
+	quest.GET("/check/status", CheckQuestStatusHandler)
 	quest.POST("/twitter_link", PostTwitterLinkHandler)
+	quest.POST("/submission", SubmitProofHandler)
+	quest.GET("/submissions", GetSubmissionsHandler)
+	quest.GET("/quiz", GetQuizHandler)
+	quest.POST("/quiz/submit", SubmitQuizHandler)
+	quest.GET("/tracking_link", GetTrackingLinkHandler)
 	quest.POST("/kol_referral_code", BindingKOLReferralCodeHandler)
 	quest.GET("/official_website/brows", BrowsOfficialWebsite)
 	quest.GET("/official_website/verify", VerifyBrowsOfficialWebsite)
@@ -132,6 +148,34 @@ func ServerAPI(cfg *config.Config) {
 
 	quest.GET("/invite/logs", GetInviteLogs)
 	quest.GET("/mission/logs", GetMissionLogs)
+	quest.GET("/credit/logs", GetCreditLogs)
+
+	admin := apiV1.Group("/admin")
+	admin.Use(authMiddleware.MiddlewareFunc(), AdminMiddleware())
+	admin.GET("/missions", AdminListMissionsHandler(dao.MissionTable))
+	admin.POST("/mission/create", AdminCreateMissionHandler(dao.MissionTable))
+	admin.POST("/mission/update", AdminUpdateMissionHandler(dao.MissionTable))
+	admin.POST("/mission/sort", AdminSortMissionsHandler(dao.MissionTable))
+	admin.POST("/mission/status", AdminMissionStatusHandler(dao.MissionTable))
+	admin.GET("/sub_missions", AdminListMissionsHandler(dao.SubMissionTable))
+	admin.POST("/sub_mission/create", AdminCreateMissionHandler(dao.SubMissionTable))
+	admin.POST("/sub_mission/update", AdminUpdateMissionHandler(dao.SubMissionTable))
+	admin.POST("/sub_mission/sort", AdminSortMissionsHandler(dao.SubMissionTable))
+	admin.POST("/sub_mission/status", AdminMissionStatusHandler(dao.SubMissionTable))
+	admin.GET("/operation_logs", AdminOperationLogsHandler)
+	admin.POST("/credit/adjust", AdminAdjustCreditsHandler)
+	admin.POST("/credit/revoke", AdminRevokeCreditHandler)
+	admin.GET("/submissions", AdminSubmissionsHandler)
+	admin.POST("/submission/approve", AdminReviewSubmissionHandler(true))
+	admin.POST("/submission/reject", AdminReviewSubmissionHandler(false))
+	admin.GET("/quiz/questions", AdminQuizQuestionsHandler)
+	admin.POST("/quiz/question/save", AdminSaveQuizQuestionHandler)
+	admin.POST("/quiz/question/delete", AdminDeleteQuizQuestionHandler)
+	admin.GET("/channels", AdminChannelsHandler)
+	admin.POST("/channel/save", AdminSaveChannelHandler)
+	admin.GET("/translations", AdminTranslationsHandler)
+	admin.POST("/translation/save", AdminSaveTranslationHandler)
+	admin.POST("/translation/delete", AdminDeleteTranslationHandler)
 
 	if err := r.Run(cfg.ApiListen); err != nil {
 		log.Fatalf("starting server: %v\n", err)
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// 赛季所处的阶段
+const (
+	SeasonStateUpcoming = "upcoming"
+	SeasonStateCurrent  = "current"
+	SeasonStateEnded    = "ended"
+	SeasonStateArchived = "archived"
+)
+
+// RespSeason 赛季及其积分统计
+type RespSeason struct {
+	*model.Season
+	State        string `json:"state"`
+	Participants int64  `json:"participants"`
+	Credits      int64  `json:"credits"`
+}
+
+func seasonState(season *model.Season, now time.Time) string {
+	switch {
+	case season.Status == model.SeasonStatusArchived:
+		return SeasonStateArchived
+	case now.Before(season.StartTime):
+		return SeasonStateUpcoming
+	case season.EndTime.After(season.StartTime) && !now.Before(season.EndTime):
+		return SeasonStateEnded
+	default:
+		return SeasonStateCurrent
+	}
+}
+
+// currentSeasonId 获取当前赛季id, 没有进行中的赛季时返回 0
+func currentSeasonId(ctx context.Context) (int64, error) {
+	season, err := dao.GetCurrentSeason(ctx, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return season.ID, nil
+}
+
+// querySeasonId 解析请求中的赛季过滤参数 season_id, 未传时使用当前赛季, 传 0 时不按赛季过滤
+func querySeasonId(c *gin.Context) (int64, error) {
+	value, ok := c.GetQuery("season_id")
+	if !ok || value == "" {
+		return currentSeasonId(c.Request.Context())
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid season_id")
+	}
+
+	return id, nil
+}
+
+// inSeason 判断任务是否属于赛季, 不属于任何赛季的任务在所有赛季中都展示
+func inSeason(mission *model.Mission, seasonId int64) bool {
+	return seasonId == 0 || mission.SeasonID == 0 || mission.SeasonID == seasonId
+}
+
+// seasonSubMissions 过滤不属于赛季的子任务, 未设置赛季的子任务跟随父任务
+func seasonSubMissions(subMissions []*model.Mission, seasonId int64) []*model.Mission {
+	out := make([]*model.Mission, 0, len(subMissions))
+	for _, sub := range subMissions {
+		if inSeason(sub, seasonId) {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+// GetSeasonsHandler 获取全部赛季, 包括已归档的赛季
+func GetSeasonsHandler(c *gin.Context) {
+	seasons, err := dao.GetSeasons(c.Request.Context())
+	if err != nil {
+		log.Errorf("GetSeasons: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	totals, err := dao.GetSeasonTotals(c.Request.Context())
+	if err != nil {
+		log.Errorf("GetSeasonTotals: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	now := time.Now()
+	out := make([]*RespSeason, 0, len(seasons))
+	for _, season := range seasons {
+		resp := &RespSeason{
+			Season: season,
+			State:  seasonState(season, now),
+		}
+
+		if total, ok := totals[season.ID]; ok {
+			resp.Participants = total.Participants
+			resp.Credits = total.Credits
+		}
+
+		out = append(out, resp)
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"list": out,
+	}))
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeasonState(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.Local)
+
+	season := &model.Season{
+		Status:    model.SeasonStatusActive,
+		StartTime: now.Add(time.Hour),
+		EndTime:   now.Add(30 * 24 * time.Hour),
+	}
+	require.Equal(t, SeasonStateUpcoming, seasonState(season, now))
+
+	season.StartTime = now.Add(-time.Hour)
+	require.Equal(t, SeasonStateCurrent, seasonState(season, now))
+
+	season.EndTime = now
+	require.Equal(t, SeasonStateEnded, seasonState(season, now))
+
+	// end time not after start time means the season never ends
+	season.EndTime = season.StartTime
+	require.Equal(t, SeasonStateCurrent, seasonState(season, now))
+
+	season.Status = model.SeasonStatusArchived
+	require.Equal(t, SeasonStateArchived, seasonState(season, now))
+}
+
+func TestInSeason(t *testing.T) {
+	require.True(t, inSeason(&model.Mission{SeasonID: 0}, 2))
+	require.True(t, inSeason(&model.Mission{SeasonID: 2}, 2))
+	require.False(t, inSeason(&model.Mission{SeasonID: 1}, 2))
+	require.True(t, inSeason(&model.Mission{SeasonID: 1}, 0))
+
+	subs := seasonSubMissions([]*model.Mission{{ID: 1}, {ID: 2, SeasonID: 1}, {ID: 3, SeasonID: 2}}, 2)
+	require.Len(t, subs, 2)
+	require.Equal(t, int64(1), subs[0].ID)
+	require.Equal(t, int64(3), subs[1].ID)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/pkg/streak"
+)
+
+// RespStreak 每日任务的连续完成情况
+type RespStreak struct {
+	MissionID     int64  `json:"mission_id"`
+	CurrentStreak int64  `json:"current_streak"`
+	LongestStreak int64  `json:"longest_streak"`
+	LastDay       string `json:"last_day"`
+	// 下一次完成时的连续奖励比例
+	BonusPercent int64 `json:"bonus_percent"`
+}
+
+// getUserStreaks 获取用户每日任务的连续完成情况, 昨天之前中断的连续天数按 0 返回
+func getUserStreaks(ctx context.Context, username string, now time.Time) ([]*RespStreak, error) {
+	streaks, err := dao.GetUserStreaks(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	today := streak.Day(now, dao.StreakLocation())
+	tiers := dao.StreakTiers()
+
+	out := make([]*RespStreak, 0, len(streaks))
+	for _, us := range streaks {
+		state := streak.State{
+			Current: us.CurrentStreak,
+			Longest: us.LongestStreak,
+			LastDay: us.LastDay,
+		}
+
+		out = append(out, &RespStreak{
+			MissionID:     us.MissionID,
+			CurrentStreak: state.Active(today),
+			LongestStreak: us.LongestStreak,
+			LastDay:       us.LastDay,
+			BonusPercent:  streak.BonusPercent(tiers, state.Advance(today).Current),
+		})
+	}
+
+	return out, nil
+}
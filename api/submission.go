@@ -0,0 +1,444 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+const (
+	maxSubmissionUrls    = 10
+	maxSubmissionContent = 5000
+	maxRejectReason      = 512
+)
+
+// 提交状态名称, 在任务列表中展示
+const (
+	SubmissionStatePending  = "pending"
+	SubmissionStateApproved = "approved"
+	SubmissionStateRejected = "rejected"
+)
+
+func submissionStateName(status int32) string {
+	switch status {
+	case dao.SubmissionStatusApproved:
+		return SubmissionStateApproved
+	case dao.SubmissionStatusRejected:
+		return SubmissionStateRejected
+	default:
+		return SubmissionStatePending
+	}
+}
+
+// RespReview 人工审核任务在当前周期的审核状态
+type RespReview struct {
+	SubmissionID int64  `json:"submission_id"`
+	Status       string `json:"status"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// SubmitProofRequest 提交人工审核任务的完成凭证
+type SubmitProofRequest struct {
+	MissionID int64    `json:"mission_id"`
+	Urls      []string `json:"urls"`
+	Content   string   `json:"content"`
+}
+
+// validate 校验提交内容, 链接必须是 http(s) 地址, 链接和文字说明至少提交一项
+func (r *SubmitProofRequest) validate() error {
+	if r.MissionID <= 0 {
+		return errors.New("mission_id is required")
+	}
+
+	r.Content = strings.TrimSpace(r.Content)
+	if utf8.RuneCountInString(r.Content) > maxSubmissionContent {
+		return fmt.Errorf("content must not exceed %d characters", maxSubmissionContent)
+	}
+
+	var urls []string
+	for _, u := range r.Urls {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid url: %s", u)
+		}
+		urls = append(urls, u)
+	}
+
+	if len(urls) > maxSubmissionUrls {
+		return fmt.Errorf("at most %d urls are allowed", maxSubmissionUrls)
+	}
+
+	if len(urls) == 0 && r.Content == "" {
+		return errors.New("urls or content is required")
+	}
+
+	r.Urls = urls
+	return nil
+}
+
+// SubmitProofHandler 提交人工审核任务的完成凭证, 审核通过后发放积分
+func SubmitProofHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	var req SubmitProofRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, err, c))
+		return
+	}
+
+	mission, err := getMissionById(c.Request.Context(), req.MissionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && mission.Verifier != VerifierManualReview) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("getMissionById: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	queryOpt, code := checkUserMissionSubmittable(c.Request.Context(), mission, username, time.Now())
+	if code != 0 {
+		c.JSON(http.StatusOK, respErrorCode(code, c))
+		return
+	}
+
+	urls, err := json.Marshal(req.Urls)
+	if err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	submission := &model.MissionSubmission{
+		Username:  username,
+		MissionID: mission.ID,
+		Period:    awardPeriod(queryOpt),
+		Urls:      urls,
+		Content:   req.Content,
+		Status:    dao.SubmissionStatusPending,
+	}
+
+	err = dao.AddMissionSubmission(c.Request.Context(), submission)
+	if errors.Is(err, dao.ErrSubmissionExists) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.MissionUnderReview, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("AddMissionSubmission: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"submission_id": submission.ID,
+		"status":        SubmissionStatePending,
+	}))
+}
+
+// checkUserMissionSubmittable 校验用户当前能否提交任务, 返回任务当前周期的查询范围, 不能提交时返回错误码
+func checkUserMissionSubmittable(ctx context.Context, mission *model.Mission, username string, now time.Time) (dao.QueryOption, int) {
+	if code := missionStateErrorCode(missionState(mission, now)); code != 0 {
+		return dao.QueryOption{}, code
+	}
+
+	if mission.SoldOut(mission.Credit) {
+		return dao.QueryOption{}, errorsx.MissionSoldOut
+	}
+
+	graph, err := loadMissionGraph(ctx)
+	if err != nil {
+		log.Errorf("loadMissionGraph: %v", err)
+		return dao.QueryOption{}, errorsx.InternalServer
+	}
+
+	completed, err := getUserCompletedMissions(ctx, username)
+	if err != nil {
+		log.Errorf("getUserCompletedMissions: %v", err)
+		return dao.QueryOption{}, errorsx.InternalServer
+	}
+
+	if !graph.IsUnlocked(mission.ID, completed) {
+		return dao.QueryOption{}, errorsx.MissionLocked
+	}
+
+	window, err := missionWindow(mission, now)
+	if err != nil {
+		log.Errorf("missionWindow: %v", err)
+		return dao.QueryOption{}, errorsx.InternalServer
+	}
+
+	return windowQueryOption(window), 0
+}
+
+// GetSubmissionsHandler 获取用户的提交记录
+func GetSubmissionsHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	missionId, _ := strconv.ParseInt(c.Query("mission_id"), 10, 64)
+	page, _ := strconv.Atoi(c.Query("page"))
+	size, _ := strconv.Atoi(c.Query("size"))
+
+	filter := dao.SubmissionFilter{Status: -1, MissionID: missionId, Username: username}
+	out, total, err := dao.GetMissionSubmissions(c.Request.Context(), filter, dao.QueryOption{Page: page, PageSize: size})
+	if err != nil {
+		log.Errorf("GetMissionSubmissions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"total": total,
+		"list":  out,
+	}))
+}
+
+// getUserReviews 获取用户每个人工审核任务在当前周期内最近一次提交的审核状态
+func getUserReviews(ctx context.Context, username string, missions []*model.Mission, now time.Time) (map[int64]*RespReview, error) {
+	out := make(map[int64]*RespReview)
+	if username == "" {
+		return out, nil
+	}
+
+	periods := make(map[int64]string)
+	for _, mission := range missions {
+		if mission.Verifier != VerifierManualReview {
+			continue
+		}
+
+		window, err := missionWindow(mission, now)
+		if err != nil {
+			continue
+		}
+		periods[mission.ID] = awardPeriod(windowQueryOption(window))
+	}
+
+	if len(periods) == 0 {
+		return out, nil
+	}
+
+	submissions, err := dao.GetUserLatestSubmissions(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range submissions {
+		if period, ok := periods[s.MissionID]; !ok || period != s.Period {
+			continue
+		}
+
+		out[s.MissionID] = &RespReview{
+			SubmissionID: s.ID,
+			Status:       submissionStateName(s.Status),
+			Reason:       s.Reason,
+		}
+	}
+
+	return out, nil
+}
+
+// checkManualReview 人工审核任务的校验器, 当前周期内的提交审核通过后发放积分
+func checkManualReview(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	submission, err := dao.GetLatestMissionSubmission(ctx, username, mission.ID, awardPeriod(queryOpt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("please submit the proof first")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	switch submission.Status {
+	case dao.SubmissionStatusApproved:
+		return awardSubmission(ctx, mission, submission)
+	case dao.SubmissionStatusRejected:
+		return fmt.Errorf("submission was rejected: %s", submission.Reason)
+	default:
+		return errors.New("submission is under review")
+	}
+}
+
+// submissionUserMission 审核通过的提交对应的完成记录
+func submissionUserMission(mission *model.Mission, submission *model.MissionSubmission) *model.UserMission {
+	return &model.UserMission{
+		Username:  submission.Username,
+		MissionID: mission.ID,
+		Type:      mission.Type,
+		Credit:    mission.Credit,
+		Content:   strconv.FormatInt(submission.ID, 10),
+		Period:    submission.Period,
+		CreatedAt: time.Now(),
+	}
+}
+
+// awardSubmission 为审核通过的提交发放积分, 同一周期只发放一次
+func awardSubmission(ctx context.Context, mission *model.Mission, submission *model.MissionSubmission) error {
+	um := submissionUserMission(mission, submission)
+	err := dao.AddUserMissionAndInviteLog(ctx, um)
+	if err != nil && !errors.Is(err, dao.ErrAlreadyAwarded) {
+		return err
+	}
+
+	// 同一周期已发放时关联已有的完成记录, 并返回 ErrAlreadyAwarded
+	submission.UserMissionID = um.ID
+	return err
+}
+
+// AdminSubmissionsHandler 获取审核队列, 默认只返回待审核的提交
+func AdminSubmissionsHandler(c *gin.Context) {
+	filter := dao.SubmissionFilter{Status: dao.SubmissionStatusPending, Username: c.Query("username")}
+	filter.MissionID, _ = strconv.ParseInt(c.Query("mission_id"), 10, 64)
+
+	switch c.Query("status") {
+	case "", SubmissionStatePending:
+	case SubmissionStateApproved:
+		filter.Status = dao.SubmissionStatusApproved
+	case SubmissionStateRejected:
+		filter.Status = dao.SubmissionStatusRejected
+	case "all":
+		filter.Status = -1
+	default:
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	size, _ := strconv.Atoi(c.Query("size"))
+
+	out, total, err := dao.GetMissionSubmissions(c.Request.Context(), filter, dao.QueryOption{Page: page, PageSize: size})
+	if err != nil {
+		log.Errorf("GetMissionSubmissions: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"total": total,
+		"list":  out,
+	}))
+}
+
+// AdminReviewSubmissionHandler 审核提交, 通过时按正常流程发放积分, 拒绝时需要填写原因
+func AdminReviewSubmissionHandler(approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := jwt.ExtractClaims(c)
+		operator := claims[identityKey].(string)
+
+		var req struct {
+			ID     int64  `json:"id"`
+			Reason string `json:"reason"`
+		}
+
+		if err := c.BindJSON(&req); err != nil || req.ID <= 0 {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InvalidParams, c))
+			return
+		}
+
+		req.Reason = strings.TrimSpace(req.Reason)
+		if !approve && (req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxRejectReason) {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, errors.New("reason is required"), c))
+			return
+		}
+
+		submission, err := dao.GetMissionSubmission(c.Request.Context(), req.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+			return
+		}
+
+		if err != nil {
+			log.Errorf("GetMissionSubmission: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		if submission.Status != dao.SubmissionStatusPending {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, errors.New("submission has been reviewed"), c))
+			return
+		}
+
+		mission, err := getMissionById(c.Request.Context(), submission.MissionID)
+		if err != nil {
+			log.Errorf("getMissionById: %v", err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		title, notificationType := "reject submission", NotificationSubmissionRejected
+		submission.Status = dao.SubmissionStatusRejected
+		submission.Reason = req.Reason
+		submission.Reviewer = operator
+
+		if approve {
+			title, notificationType = "approve submission", NotificationSubmissionApproved
+			submission.Reason = ""
+
+			// 发放积分与修改审核状态在同一事务中完成, 与并发的拒绝互斥, 发放失败时提交仍为待审核
+			err = dao.ApproveMissionSubmission(c.Request.Context(), submission, submissionUserMission(mission, submission))
+			if errors.Is(err, dao.ErrAlreadyAwarded) {
+				log.Warnf("submission %d approved without credit: %v", submission.ID, err)
+				err = nil
+			}
+
+			if errors.Is(err, dao.ErrMissionSoldOut) {
+				recordOperation(c, title, BusinessTypeStatus, req, nil, err)
+				c.JSON(http.StatusOK, respErrorCode(errorsx.MissionSoldOut, c))
+				return
+			}
+		} else {
+			err = dao.ReviewMissionSubmission(c.Request.Context(), submission)
+		}
+
+		recordOperation(c, title, BusinessTypeStatus, req, submission, err)
+		if errors.Is(err, dao.ErrNoRow) {
+			c.JSON(http.StatusOK, respErrorMessage(errorsx.InvalidParams, errors.New("submission has been reviewed"), c))
+			return
+		}
+
+		if err != nil {
+			log.Errorf("review submission %d: %v", submission.ID, err)
+			c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+			return
+		}
+
+		err = addNotification(c.Request.Context(), submission.Username, notificationType, notificationParams{
+			MissionID: mission.ID,
+			Title:     mission.Title,
+			TitleCn:   mission.TitleCn,
+			Credit:    mission.Credit,
+			Reason:    submission.Reason,
+		})
+		if err != nil {
+			log.Errorf("addNotification: %v", err)
+		}
+
+		c.JSON(http.StatusOK, respJSON(submission))
+	}
+}
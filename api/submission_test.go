@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitProofRequest(t *testing.T) {
+	req := &SubmitProofRequest{MissionID: 1, Urls: []string{" https://medium.com/@titan/article ", ""}, Content: " translated docs "}
+	require.NoError(t, req.validate())
+	require.Equal(t, []string{"https://medium.com/@titan/article"}, req.Urls)
+	require.Equal(t, "translated docs", req.Content)
+
+	require.Error(t, (&SubmitProofRequest{MissionID: 1}).validate())
+	require.Error(t, (&SubmitProofRequest{Urls: []string{"https://titannet.io"}}).validate())
+	require.Error(t, (&SubmitProofRequest{MissionID: 1, Urls: []string{"javascript:alert(1)"}}).validate())
+	require.Error(t, (&SubmitProofRequest{MissionID: 1, Content: strings.Repeat("a", maxSubmissionContent+1)}).validate())
+
+	urls := make([]string, maxSubmissionUrls+1)
+	for i := range urls {
+		urls[i] = "https://titannet.io"
+	}
+	require.Error(t, (&SubmitProofRequest{MissionID: 1, Urls: urls}).validate())
+}
+
+func TestSubmissionState(t *testing.T) {
+	require.Equal(t, SubmissionStatePending, submissionStateName(dao.SubmissionStatusPending))
+	require.Equal(t, SubmissionStateApproved, submissionStateName(dao.SubmissionStatusApproved))
+	require.Equal(t, SubmissionStateRejected, submissionStateName(dao.SubmissionStatusRejected))
+
+	// anonymous users and missions without manual review need no query
+	reviews, err := getUserReviews(context.Background(), "", []*model.Mission{{ID: 1, Verifier: VerifierManualReview}}, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, reviews)
+
+	reviews, err = getUserReviews(context.Background(), "user", []*model.Mission{{ID: 1, Verifier: VerifierFollowTwitter}}, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, reviews)
+
+	require.NoError(t, ValidateMission(&model.Mission{Verifier: VerifierManualReview, Type: MissionTypeBasic}))
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+const (
+	trackingSignatureSize = 16
+	maxUserAgentLength    = 512
+	// trackingOwnerTTL 链接所属用户获取链接时使用的 ip 保留的时间
+	trackingOwnerTTL = 7 * 24 * time.Hour
+)
+
+// 链接预览等爬虫的 User-Agent, 这些访问不计入独立访问
+var trackingBotAgents = []string{"bot", "spider", "crawler", "facebookexternalhit", "preview", "curl", "wget"}
+
+var errInvalidTrackingToken = errors.New("invalid tracking token")
+
+func isTrackingVerifier(verifier string) bool {
+	return verifier == VerifierVisitOfficialWebsite || verifier == VerifierVisitReferrerPage
+}
+
+func trackingSignature(payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(config.Cfg.SecretKey))
+	mac.Write([]byte("tracking:" + payload))
+	return mac.Sum(nil)[:trackingSignatureSize]
+}
+
+// signTrackingToken 生成用户访问任务的追踪链接 token, 格式为 base64(<mission_id>:<username>).base64(签名)
+func signTrackingToken(missionId int64, username string) string {
+	payload := fmt.Sprintf("%d:%s", missionId, username)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(trackingSignature(payload))
+}
+
+// parseTrackingToken 校验追踪链接 token 的签名, 返回任务id及链接所属的用户
+func parseTrackingToken(token string) (int64, string, error) {
+	encoded, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", errInvalidTrackingToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, "", errInvalidTrackingToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, trackingSignature(string(payload))) {
+		return 0, "", errInvalidTrackingToken
+	}
+
+	id, username, ok := strings.Cut(string(payload), ":")
+	missionId, err := strconv.ParseInt(id, 10, 64)
+	if !ok || err != nil || username == "" {
+		return 0, "", errInvalidTrackingToken
+	}
+
+	return missionId, username, nil
+}
+
+// trackingLink 用户的追踪链接
+func trackingLink(c *gin.Context, missionId int64, username string) string {
+	base := strings.TrimRight(config.Cfg.TrackingLinkURI, "/")
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = fmt.Sprintf("%s://%s/api/v1/track", scheme, c.Request.Host)
+	}
+	return base + "/" + signTrackingToken(missionId, username)
+}
+
+// trackingTarget 追踪链接跳转的页面, 官网访问任务未配置 target_url 时跳转到官网
+func trackingTarget(mission *model.Mission, params *model.MissionParams, user *model.User) string {
+	target := params.TargetURL
+	if target == "" && mission.Verifier == VerifierVisitOfficialWebsite {
+		target = config.Cfg.OfficialWebsiteURI
+	}
+	return strings.ReplaceAll(target, "{referral_code}", url.QueryEscape(user.ReferralCode))
+}
+
+func requiredVisits(params *model.MissionParams) int64 {
+	if params.RequiredVisits <= 0 {
+		return 1
+	}
+	return int64(params.RequiredVisits)
+}
+
+// visitFingerprint 访问者的指纹, 根据请求头计算, 不使用客户端传入的参数
+func visitFingerprint(c *gin.Context) string {
+	sum := sha256.Sum256([]byte(c.GetHeader("User-Agent") + "|" + c.GetHeader("Accept-Language")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func isTrackingBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+
+	ua := strings.ToLower(userAgent)
+	for _, bot := range trackingBotAgents {
+		if strings.Contains(ua, bot) {
+			return true
+		}
+	}
+	return false
+}
+
+// getTrackingMission 获取访问任务及其校验参数, 不是访问任务时返回 sql.ErrNoRows
+func getTrackingMission(ctx context.Context, missionId int64) (*model.Mission, *model.MissionParams, error) {
+	mission, err := getMissionById(ctx, missionId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !isTrackingVerifier(mission.Verifier) {
+		return nil, nil, sql.ErrNoRows
+	}
+
+	params, err := mission.GetParams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mission, params, nil
+}
+
+// GetTrackingLinkHandler 获取用户访问任务的追踪链接及当前周期的独立访问次数
+func GetTrackingLinkHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	username := claims[identityKey].(string)
+
+	missionId, _ := strconv.ParseInt(c.Query("mission_id"), 10, 64)
+	mission, params, err := getTrackingMission(c.Request.Context(), missionId)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("getTrackingMission: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	now := time.Now()
+	if code := missionStateErrorCode(missionState(mission, now)); code != 0 {
+		c.JSON(http.StatusOK, respErrorCode(code, c))
+		return
+	}
+
+	window, err := missionWindow(mission, now)
+	if err != nil {
+		log.Errorf("missionWindow: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	visits, err := dao.CountTrackingVisits(c.Request.Context(), username, mission.ID, awardPeriod(windowQueryOption(window)))
+	if err != nil {
+		log.Errorf("CountTrackingVisits: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	// 链接所属用户自己的访问不计入独立访问
+	if err := dao.AddTrackingOwnerIP(c.Request.Context(), username, c.ClientIP(), trackingOwnerTTL); err != nil {
+		log.Errorf("AddTrackingOwnerIP: %v", err)
+	}
+
+	c.JSON(http.StatusOK, respJSON(JsonObject{
+		"link":            trackingLink(c, mission.ID, username),
+		"visits":          visits,
+		"required_visits": requiredVisits(params),
+	}))
+}
+
+// TrackingVisitHandler 记录追踪链接的访问并跳转到目标页面, 独立访问次数达到要求后完成任务
+func TrackingVisitHandler(c *gin.Context) {
+	missionId, username, err := parseTrackingToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	mission, params, err := getTrackingMission(c.Request.Context(), missionId)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("getTrackingMission: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	user, err := dao.GetUserByUsername(c.Request.Context(), username)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, respErrorCode(errorsx.NotFound, c))
+		return
+	}
+
+	if err != nil {
+		log.Errorf("GetUserByUsername: %v", err)
+		c.JSON(http.StatusOK, respErrorCode(errorsx.InternalServer, c))
+		return
+	}
+
+	// 记录失败不影响跳转
+	if err := recordTrackingVisit(c, mission, params, username); err != nil {
+		log.Errorf("recordTrackingVisit: %v", err)
+	}
+
+	target := trackingTarget(mission, params, user)
+	if target == "" {
+		c.JSON(http.StatusOK, respJSON(nil))
+		return
+	}
+
+	c.Redirect(http.StatusFound, target)
+}
+
+// recordTrackingVisit 记录独立访问, 访问次数达到要求且任务可以完成时发放积分.
+// 爬虫及链接所属用户获取链接时使用的 ip 的访问不计入
+func recordTrackingVisit(c *gin.Context, mission *model.Mission, params *model.MissionParams, username string) error {
+	userAgent := c.GetHeader("User-Agent")
+	if isTrackingBot(userAgent) {
+		return nil
+	}
+
+	ip := c.ClientIP()
+	owner, err := dao.IsTrackingOwnerIP(c.Request.Context(), username, ip)
+	if err != nil || owner {
+		return err
+	}
+
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	queryOpt, code := checkUserMissionSubmittable(c.Request.Context(), mission, username, time.Now())
+	if code != 0 {
+		return nil
+	}
+
+	inserted, err := dao.AddTrackingVisit(c.Request.Context(), &model.TrackingVisit{
+		Username:    username,
+		MissionID:   mission.ID,
+		Period:      awardPeriod(queryOpt),
+		Ip:          ip,
+		Fingerprint: visitFingerprint(c),
+		UserAgent:   userAgent,
+	})
+	if err != nil || !inserted {
+		return err
+	}
+
+	visits, err := dao.CountTrackingVisits(c.Request.Context(), username, mission.ID, awardPeriod(queryOpt))
+	if err != nil {
+		return err
+	}
+
+	if visits < requiredVisits(params) {
+		return nil
+	}
+
+	err = awardTrackingVisits(c.Request.Context(), mission, username, queryOpt, visits)
+	if errors.Is(err, dao.ErrAlreadyAwarded) {
+		return nil
+	}
+	return err
+}
+
+// checkTrackingVisits 访问任务的校验器, 追踪链接在当前周期内的独立访问次数达到要求后发放积分
+func checkTrackingVisits(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	params, err := mission.GetParams()
+	if err != nil {
+		return err
+	}
+
+	visits, err := dao.CountTrackingVisits(ctx, username, mission.ID, awardPeriod(queryOpt))
+	if err != nil {
+		return err
+	}
+
+	if required := requiredVisits(params); visits < required {
+		return fmt.Errorf("unique visits %d/%d", visits, required)
+	}
+
+	return awardTrackingVisits(ctx, mission, username, queryOpt, visits)
+}
+
+func awardTrackingVisits(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption, visits int64) error {
+	return dao.AddUserMissionAndInviteLog(ctx, &model.UserMission{
+		Username:  username,
+		MissionID: mission.ID,
+		Type:      mission.Type,
+		Credit:    mission.Credit,
+		Content:   strconv.FormatInt(visits, 10),
+		Period:    awardPeriod(queryOpt),
+		CreatedAt: time.Now(),
+	})
+}
+
+func requireTargetURL(mission *model.Mission, params *model.MissionParams) error {
+	if params.TargetURL == "" {
+		return errors.New("missing target_url")
+	}
+	return nil
+}
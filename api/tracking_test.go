@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackingToken(t *testing.T) {
+	defer func(key string) { config.Cfg.SecretKey = key }(config.Cfg.SecretKey)
+	config.Cfg.SecretKey = "test"
+
+	token := signTrackingToken(1008, "user@titannet.io")
+	missionId, username, err := parseTrackingToken(token)
+	require.NoError(t, err)
+	require.Equal(t, int64(1008), missionId)
+	require.Equal(t, "user@titannet.io", username)
+
+	// tampered payload or signature
+	other := signTrackingToken(1009, "user@titannet.io")
+	payload, _, _ := strings.Cut(other, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	_, _, err = parseTrackingToken(payload + "." + sig)
+	require.ErrorIs(t, err, errInvalidTrackingToken)
+
+	_, _, err = parseTrackingToken(token + "x")
+	require.ErrorIs(t, err, errInvalidTrackingToken)
+
+	_, _, err = parseTrackingToken("invalid")
+	require.ErrorIs(t, err, errInvalidTrackingToken)
+
+	config.Cfg.SecretKey = "another"
+	_, _, err = parseTrackingToken(token)
+	require.ErrorIs(t, err, errInvalidTrackingToken)
+}
+
+func TestTrackingTarget(t *testing.T) {
+	defer func(uri string) { config.Cfg.OfficialWebsiteURI = uri }(config.Cfg.OfficialWebsiteURI)
+	config.Cfg.OfficialWebsiteURI = "https://titannet.io"
+	user := &model.User{ReferralCode: "AB C"}
+
+	mission := &model.Mission{Verifier: VerifierVisitOfficialWebsite}
+	require.Equal(t, "https://titannet.io", trackingTarget(mission, &model.MissionParams{}, user))
+
+	mission = &model.Mission{Verifier: VerifierVisitReferrerPage}
+	params := &model.MissionParams{TargetURL: "https://titannet.io/invite?code={referral_code}"}
+	require.Equal(t, "https://titannet.io/invite?code=AB+C", trackingTarget(mission, params, user))
+
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierVisitReferrerPage, Type: MissionTypeBasic}))
+	require.Error(t, ValidateMission(&model.Mission{Verifier: VerifierVisitReferrerPage, Type: MissionTypeBasic, Params: []byte(`{"target_url":"javascript:alert(1)"}`)}))
+	require.NoError(t, ValidateMission(&model.Mission{Verifier: VerifierVisitReferrerPage, Type: MissionTypeBasic, Params: []byte(`{"target_url":"https://titannet.io/invite?code={referral_code}","required_visits":5}`)}))
+
+	require.Equal(t, int64(1), requiredVisits(&model.MissionParams{}))
+	require.Equal(t, int64(5), requiredVisits(&model.MissionParams{RequiredVisits: 5}))
+}
+
+func TestVisitFingerprint(t *testing.T) {
+	newContext := func(target, userAgent string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", target, nil)
+		c.Request.Header.Set("User-Agent", userAgent)
+		return c
+	}
+
+	chrome := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0"
+	require.Equal(t, visitFingerprint(newContext("/track/x", chrome)), visitFingerprint(newContext("/track/y", chrome)))
+	require.NotEqual(t, visitFingerprint(newContext("/track/x", chrome)), visitFingerprint(newContext("/track/x", "Safari")))
+	require.Equal(t, visitFingerprint(newContext("/track/x", chrome)), visitFingerprint(newContext("/track/x?fp=abc", chrome)))
+
+	require.False(t, isTrackingBot(chrome))
+	require.True(t, isTrackingBot("Twitterbot/1.0"))
+	require.True(t, isTrackingBot("facebookexternalhit/1.1"))
+	require.True(t, isTrackingBot(""))
+}
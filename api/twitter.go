@@ -0,0 +1,580 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	swagger "github.com/gnasnik/titan-quest/go-client-generated"
+	"github.com/golang-module/carbon/v2"
+	"github.com/valyala/fastjson"
+)
+
+const (
+	// maxTweetReplyPages 校验回复任务时最多查询的回复页数
+	maxTweetReplyPages = 5
+	// maxTweetQuotePages 校验引用任务时最多查询的引用页数
+	maxTweetQuotePages = 10
+	// maxTweetUserPages 校验点赞、转推任务时最多查询的用户列表页数
+	maxTweetUserPages = 10
+	// maxFollowingPages 校验关注任务时最多查询的关注列表页数, 每页最多 5000 个id
+	maxFollowingPages = 10
+)
+
+var errInvalidTweetURL = errors.New("invalid tweet url")
+
+// tweetHosts 推文链接的域名, 不含 www.、mobile. 等前缀
+var tweetHosts = map[string]bool{"twitter.com": true, "x.com": true}
+
+// parseTweetURL 解析推文链接中的推文id, 支持 x.com、twitter.com 及移动端链接, 如
+// https://x.com/user/status/1, https://mobile.twitter.com/user/status/1/photo/1?s=20, x.com/i/web/status/1
+func parseTweetURL(link string) (string, error) {
+	link = strings.TrimSpace(link)
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", errInvalidTweetURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "mobile.", "m."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+
+	if !tweetHosts[host] {
+		return "", errInvalidTweetURL
+	}
+
+	paths := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(paths); i++ {
+		if paths[i] != "status" && paths[i] != "statuses" {
+			continue
+		}
+
+		if _, err := strconv.ParseUint(paths[i+1], 10, 64); err == nil {
+			return paths[i+1], nil
+		}
+	}
+
+	return "", errInvalidTweetURL
+}
+
+// utoolPermanentMessages UTool 返回这些错误时重试也不会成功: 推文或用户不存在、已删除、受保护, key 无效或余额不足
+var utoolPermanentMessages = []string{
+	"not found", "does not exist", "no status found", "deleted", "protected", "suspended",
+	"invalid", "apikey", "api key", "balance", "不存在", "已删除", "无效", "余额",
+}
+
+// twitterTransientCodes 推特返回的临时错误码: 限流、服务过载及内部错误
+var twitterTransientCodes = map[int]bool{88: true, 130: true, 131: true}
+
+// utoolError UTool 接口返回失败时的错误, 已知无法通过重试恢复的错误之外均视为临时错误
+func utoolError(code int32, msg string) error {
+	log.Errorf("code: %d %s", code, msg)
+
+	lower := strings.ToLower(msg)
+	for _, m := range utoolPermanentMessages {
+		if strings.Contains(lower, m) {
+			return errors.New(msg)
+		}
+	}
+	return transient(errors.New(msg))
+}
+
+// utoolData 解析 UTool 接口返回的数据. 推特返回的错误中, 推文不存在、受保护等为永久错误, 限流等为临时错误
+func utoolData(result swagger.ResultT) (*fastjson.Value, error) {
+	if result.Code != 1 {
+		return nil, utoolError(result.Code, result.Msg)
+	}
+
+	data, ok := result.Data.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected data type: %T", result.Data)
+	}
+
+	v, err := fastjson.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := v.GetArray("errors"); len(errs) > 0 && v.Get("data") == nil {
+		code := errs[0].GetInt("code")
+		err := fmt.Errorf("twitter error %d: %s", code, errs[0].GetStringBytes("message"))
+		if twitterTransientCodes[code] {
+			return nil, transient(err)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// timelineInstructions 查找时间线的 instructions, 不同接口的时间线位于不同的路径下
+func timelineInstructions(v *fastjson.Value) []*fastjson.Value {
+	if v == nil || v.Type() != fastjson.TypeObject {
+		return nil
+	}
+
+	if instructions := v.GetArray("instructions"); instructions != nil {
+		return instructions
+	}
+
+	var out []*fastjson.Value
+	v.GetObject().Visit(func(key []byte, child *fastjson.Value) {
+		if out == nil {
+			out = timelineInstructions(child)
+		}
+	})
+	return out
+}
+
+// tweetResult 推文受限时结果包装在 TweetWithVisibilityResults 中
+func tweetResult(v *fastjson.Value) *fastjson.Value {
+	if v == nil {
+		return nil
+	}
+
+	if tweet := v.Get("tweet"); tweet != nil && string(v.GetStringBytes("__typename")) == "TweetWithVisibilityResults" {
+		return tweet
+	}
+
+	return v
+}
+
+// walkTimeline 遍历时间线中的条目, 会话及模块中的条目也会遍历, 返回下一页的游标
+func walkTimeline(v *fastjson.Value, fn func(content *fastjson.Value)) string {
+	var cursor string
+
+	visit := func(content *fastjson.Value) {
+		if content == nil {
+			return
+		}
+
+		if string(content.GetStringBytes("cursorType")) == "Bottom" {
+			cursor = string(content.GetStringBytes("value"))
+			return
+		}
+
+		fn(content)
+	}
+
+	for _, instruction := range timelineInstructions(v) {
+		entries := instruction.GetArray("entries")
+		if entry := instruction.Get("entry"); entry != nil {
+			entries = append(entries, entry)
+		}
+
+		for _, entry := range entries {
+			content := entry.Get("content")
+			visit(content)
+
+			for _, item := range content.GetArray("items") {
+				visit(item.Get("item"))
+			}
+		}
+
+		for _, item := range instruction.GetArray("moduleItems") {
+			visit(item.Get("item"))
+		}
+	}
+
+	return cursor
+}
+
+// timelineTweets 解析时间线中的推文及下一页的游标
+func timelineTweets(v *fastjson.Value) ([]*fastjson.Value, string) {
+	var tweets []*fastjson.Value
+	cursor := walkTimeline(v, func(content *fastjson.Value) {
+		if tweet := tweetResult(content.Get("itemContent", "tweet_results", "result")); tweet != nil {
+			tweets = append(tweets, tweet)
+		}
+	})
+	return tweets, cursor
+}
+
+// timelineUserIds 解析用户列表时间线中的用户id及下一页的游标
+func timelineUserIds(v *fastjson.Value) ([]string, string) {
+	var ids []string
+	cursor := walkTimeline(v, func(content *fastjson.Value) {
+		if id := content.GetStringBytes("itemContent", "user_results", "result", "rest_id"); len(id) > 0 {
+			ids = append(ids, string(id))
+		}
+	})
+	return ids, cursor
+}
+
+func tweetId(tweet *fastjson.Value) string {
+	if id := tweet.GetStringBytes("rest_id"); len(id) > 0 {
+		return string(id)
+	}
+	return string(tweet.GetStringBytes("legacy", "id_str"))
+}
+
+func tweetAuthorId(tweet *fastjson.Value) string {
+	if id := tweet.GetStringBytes("core", "user_results", "result", "rest_id"); len(id) > 0 {
+		return string(id)
+	}
+	return string(tweet.GetStringBytes("legacy", "user_id_str"))
+}
+
+// tweetCreatedAt 推文的发布时间, 格式为 Mon Jan 02 15:04:05 +0000 2006
+func tweetCreatedAt(tweet *fastjson.Value) time.Time {
+	t, _ := time.Parse(time.RubyDate, string(tweet.GetStringBytes("legacy", "created_at")))
+	return t
+}
+
+// periodStart 任务当前周期的开始时间, 不重复的任务返回零值
+func periodStart(queryOpt dao.QueryOption) time.Time {
+	if queryOpt.StartTime == "" {
+		return time.Time{}
+	}
+	t, _ := time.ParseInLocation(carbon.DateTimeLayout, queryOpt.StartTime, time.Local)
+	return t
+}
+
+// getTweet 查询推文详情
+func getTweet(ctx context.Context, id string) (*fastjson.Value, error) {
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+	option := &swagger.TwitterGetTweesApiToolsApiTweetTimelineUsingGETOpts{}
+	result, httpResp, err := client.TwitterGetTweesApiToolsApi.TweetTimelineUsingGET(ctx, GetUToolKeyByRoundRobin(), id, option)
+	if err != nil {
+		log.Errorf("TweetTimelineUsingGET: %v", err)
+		return nil, upstreamError(err, httpResp)
+	}
+
+	v, err := utoolData(result)
+	if err != nil {
+		return nil, err
+	}
+
+	tweets, _ := timelineTweets(v)
+	for _, tweet := range tweets {
+		if tweetId(tweet) == id {
+			return tweet, nil
+		}
+	}
+
+	return nil, fmt.Errorf("tweet %s not found", id)
+}
+
+// getLinkedTweet 查询用户提交的推文链接对应的推文
+func getLinkedTweet(ctx context.Context, link string) (*fastjson.Value, error) {
+	id, err := parseTweetURL(link)
+	if err != nil {
+		return nil, err
+	}
+	return getTweet(ctx, id)
+}
+
+// checkTweetKeywords 推文需要包含所有关键词, 不区分大小写
+func checkTweetKeywords(tweet *fastjson.Value, keywords []string) error {
+	text := strings.ToLower(string(tweet.GetStringBytes("legacy", "full_text")))
+	for _, keyword := range keywords {
+		if !strings.Contains(text, strings.ToLower(keyword)) {
+			return fmt.Errorf("missing keyword: %s", keyword)
+		}
+	}
+	return nil
+}
+
+// matchTweet 推文是否为用户在 since 之后发布, 且满足任务的内容要求
+func matchTweet(tweet *fastjson.Value, twitterUserId string, params *model.MissionParams, since time.Time) bool {
+	if tweetAuthorId(tweet) != twitterUserId {
+		return false
+	}
+
+	if !since.IsZero() && tweetCreatedAt(tweet).Before(since) {
+		return false
+	}
+
+	return checkTweetKeywords(tweet, params.RequiredKeywords) == nil && checkTweetContent(tweet, params) == nil
+}
+
+// matchReply 推文是否为用户对目标推文的回复
+func matchReply(tweet *fastjson.Value, twitterUserId string, params *model.MissionParams, since time.Time) bool {
+	return string(tweet.GetStringBytes("legacy", "in_reply_to_status_id_str")) == params.TweetID &&
+		matchTweet(tweet, twitterUserId, params, since)
+}
+
+// matchQuote 推文是否为用户对目标推文的引用
+func matchQuote(tweet *fastjson.Value, twitterUserId string, params *model.MissionParams, since time.Time) bool {
+	quoted := tweetResult(tweet.Get("quoted_status_result", "result"))
+	if quoted == nil {
+		return false
+	}
+	return tweetId(quoted) == params.TweetID && matchTweet(tweet, twitterUserId, params, since)
+}
+
+// findQuoteTweet 按游标翻页查询目标推文的引用, 直到找到用户的引用
+func findQuoteTweet(ctx context.Context, twitterUserId string, params *model.MissionParams, since time.Time) (*fastjson.Value, error) {
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+
+	var cursor string
+	for page := 0; page < maxTweetQuotePages; page++ {
+		option := &swagger.TwitterGetTweesApiToolsApiQuotesV2UsingGETOpts{}
+		if cursor != "" {
+			option.Cursor = optional.NewString(cursor)
+		}
+
+		result, httpResp, err := client.TwitterGetTweesApiToolsApi.QuotesV2UsingGET(ctx, GetUToolKeyByRoundRobin(), params.TweetID, option)
+		if err != nil {
+			log.Errorf("QuotesV2UsingGET: %v", err)
+			return nil, upstreamError(err, httpResp)
+		}
+
+		v, err := utoolData(result)
+		if err != nil {
+			return nil, err
+		}
+
+		tweets, next := timelineTweets(v)
+
+		expired := len(tweets) > 0
+		for _, tweet := range tweets {
+			// 引用列表中的推文都引用了目标推文, 不要求返回被引用的推文
+			if matchTweet(tweet, twitterUserId, params, since) {
+				return tweet, nil
+			}
+
+			if since.IsZero() || !tweetCreatedAt(tweet).Before(since) {
+				expired = false
+			}
+		}
+
+		// 引用按时间倒序返回, 整页都早于当前周期时不再翻页
+		if next == "" || next == cursor || expired {
+			break
+		}
+		cursor = next
+	}
+
+	return nil, errors.New("quote tweet not found")
+}
+
+// checkReplyTweet 校验用户是否回复了目标推文, 按游标翻页查询用户最近的回复, 每日任务每个周期需要新的回复
+func checkReplyTweet(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	twitterUser, err := dao.GetTwitterOauthByUsername(ctx, username)
+	if err != nil {
+		log.Errorf("GetTwitterOauthByUsername: %v", err)
+		return err
+	}
+
+	params, err := mission.GetParams()
+	if err != nil {
+		log.Errorf("GetParams: %v", err)
+		return err
+	}
+
+	since := periodStart(queryOpt)
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+
+	var cursor string
+	for page := 0; page < maxTweetReplyPages; page++ {
+		option := &swagger.TwitterGetTweesApiToolsApiUserTweetReplyUsingGETOpts{}
+		if cursor != "" {
+			option.Cursor = optional.NewString(cursor)
+		}
+
+		result, httpResp, err := client.TwitterGetTweesApiToolsApi.UserTweetReplyUsingGET(ctx, GetUToolKeyByRoundRobin(), twitterUser.TwitterUserID, option)
+		if err != nil {
+			log.Errorf("UserTweetReplyUsingGET: %v", err)
+			return upstreamError(err, httpResp)
+		}
+
+		v, err := utoolData(result)
+		if err != nil {
+			return err
+		}
+
+		tweets, next := timelineTweets(v)
+
+		expired := len(tweets) > 0
+		for _, tweet := range tweets {
+			if matchReply(tweet, twitterUser.TwitterUserID, params, since) {
+				return dao.AddUserMissionAndInviteLog(ctx, &model.UserMission{
+					Username:  username,
+					MissionID: mission.ID,
+					Type:      mission.Type,
+					Credit:    mission.Credit,
+					Content:   tweetId(tweet),
+					Period:    awardPeriod(queryOpt),
+					CreatedAt: time.Now(),
+				})
+			}
+
+			if since.IsZero() || !tweetCreatedAt(tweet).Before(since) {
+				expired = false
+			}
+		}
+
+		// 回复按时间倒序返回, 整页都早于当前周期时不再翻页
+		if next == "" || next == cursor || expired {
+			break
+		}
+		cursor = next
+	}
+
+	return errors.New("reply not found")
+}
+
+// timelineHasUser 按游标翻页查询用户列表时间线中是否包含 twitterUserId, fetch 查询游标对应的一页
+func timelineHasUser(twitterUserId string, fetch func(cursor string) (swagger.ResultT, *http.Response, error)) (bool, error) {
+	var cursor string
+	for page := 0; page < maxTweetUserPages; page++ {
+		result, httpResp, err := fetch(cursor)
+		if err != nil {
+			return false, upstreamError(err, httpResp)
+		}
+
+		v, err := utoolData(result)
+		if err != nil {
+			return false, err
+		}
+
+		ids, next := timelineUserIds(v)
+		for _, id := range ids {
+			if id == twitterUserId {
+				return true, nil
+			}
+		}
+
+		// 没有更多用户时接口仍可能返回游标
+		if len(ids) == 0 || next == "" || next == cursor {
+			break
+		}
+		cursor = next
+	}
+
+	return false, nil
+}
+
+// hasFavorited 用户是否点赞了推文
+func hasFavorited(ctx context.Context, tweetId, twitterUserId string) (bool, error) {
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+	return timelineHasUser(twitterUserId, func(cursor string) (swagger.ResultT, *http.Response, error) {
+		option := &swagger.TwitterGetTweesApiToolsApiFavoritersV2UsingGETOpts{}
+		if cursor != "" {
+			option.Cursor = optional.NewString(cursor)
+		}
+		return client.TwitterGetTweesApiToolsApi.FavoritersV2UsingGET(ctx, GetUToolKeyByRoundRobin(), tweetId, option)
+	})
+}
+
+// hasRetweeted 用户是否转推了推文
+func hasRetweeted(ctx context.Context, tweetId, twitterUserId string) (bool, error) {
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+	return timelineHasUser(twitterUserId, func(cursor string) (swagger.ResultT, *http.Response, error) {
+		option := &swagger.TwitterGetTweesApiToolsApiRetweetersV2UsingGETOpts{}
+		if cursor != "" {
+			option.Cursor = optional.NewString(cursor)
+		}
+		return client.TwitterGetTweesApiToolsApi.RetweetersV2UsingGET(ctx, GetUToolKeyByRoundRobin(), tweetId, option)
+	})
+}
+
+// friendshipFollowing 查询两个账号的关系, 返回 source 是否关注了 target
+func friendshipFollowing(ctx context.Context, sourceId, targetId string) (bool, error) {
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+	option := &swagger.TwitterFollowsApiToolsApiGetFriendshipsShowUsingGETOpts{
+		SourceId: optional.NewString(sourceId),
+		TargetId: optional.NewString(targetId),
+	}
+
+	result, httpResp, err := client.TwitterFollowsApiToolsApi.GetFriendshipsShowUsingGET(ctx, GetUToolKeyByRoundRobin(), option)
+	if err != nil {
+		return false, upstreamError(err, httpResp)
+	}
+
+	v, err := utoolData(result)
+	if err != nil {
+		return false, err
+	}
+
+	return parseFriendship(v)
+}
+
+// parseFriendship 解析 friendships/show 的结果
+func parseFriendship(v *fastjson.Value) (bool, error) {
+	following := v.Get("relationship", "source", "following")
+	if following == nil {
+		return false, errors.New("missing relationship")
+	}
+	return following.GetBool(), nil
+}
+
+type followingIdsResp struct {
+	Ids           []int64 `json:"ids"`
+	NextCursorStr string  `json:"next_cursor_str"`
+}
+
+// followingIdsContains 按游标翻页查询用户的关注列表中是否包含 targetId
+func followingIdsContains(ctx context.Context, twitterUserId string, targetId int64) (bool, error) {
+	client := swagger.NewAPIClient(swagger.NewConfiguration())
+
+	var cursor string
+	for page := 0; page < maxFollowingPages; page++ {
+		option := &swagger.TwitterFollowsApiToolsApiFollowingsIdsUsingGETOpts{
+			UserId: optional.NewString(twitterUserId),
+		}
+		if cursor != "" {
+			option.Cursor = optional.NewString(cursor)
+		}
+
+		result, httpResp, err := client.TwitterFollowsApiToolsApi.FollowingsIdsUsingGET(ctx, GetUToolKeyByRoundRobin(), option)
+		if err != nil {
+			log.Errorf("FollowingsIdsUsingGET: %v", err)
+			return false, upstreamError(err, httpResp)
+		}
+
+		if result.Code != 1 {
+			return false, utoolError(result.Code, result.Msg)
+		}
+
+		data, ok := result.Data.(string)
+		if !ok {
+			return false, errors.New("response not string")
+		}
+
+		var resp followingIdsResp
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			return false, err
+		}
+
+		for _, id := range resp.Ids {
+			if id == targetId {
+				return true, nil
+			}
+		}
+
+		// 最后一页的游标为 0
+		if resp.NextCursorStr == "" || resp.NextCursorStr == "0" || resp.NextCursorStr == cursor {
+			break
+		}
+		cursor = resp.NextCursorStr
+	}
+
+	return false, nil
+}
+
+// isFollowing 用户是否关注了目标账号, 优先查询两者的关系, 查询失败时再翻页查询用户的关注列表
+func isFollowing(ctx context.Context, twitterUserId string, targetId int64) (bool, error) {
+	following, err := friendshipFollowing(ctx, twitterUserId, strconv.FormatInt(targetId, 10))
+	if err == nil {
+		return following, nil
+	}
+
+	log.Warnf("friendshipFollowing: %v", err)
+	return followingIdsContains(ctx, twitterUserId, targetId)
+}
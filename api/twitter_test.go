@@ -0,0 +1,144 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	swagger "github.com/gnasnik/titan-quest/go-client-generated"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fastjson"
+)
+
+const replyTimeline = `{"data":{"user":{"result":{"timeline_v2":{"timeline":{"instructions":[
+{"type":"TimelineAddEntries","entries":[
+{"entryId":"profile-conversation-1","content":{"entryType":"TimelineTimelineModule","items":[
+{"item":{"itemContent":{"tweet_results":{"result":{"__typename":"Tweet","rest_id":"100","core":{"user_results":{"result":{"rest_id":"9"}}},"legacy":{"created_at":"Tue May 07 08:00:00 +0000 2024","full_text":"Titan announcement","user_id_str":"9"}}}}}},
+{"item":{"itemContent":{"tweet_results":{"result":{"__typename":"TweetWithVisibilityResults","tweet":{"rest_id":"101","core":{"user_results":{"result":{"rest_id":"1"}}},"legacy":{"created_at":"Tue May 07 09:00:00 +0000 2024","full_text":"@Titannet_dao Great work on #Titan storage!","in_reply_to_status_id_str":"100","user_id_str":"1","entities":{"hashtags":[{"text":"Titan"}]}}}}}}}}
+]}},
+{"entryId":"cursor-bottom-1","content":{"entryType":"TimelineTimelineCursor","cursorType":"Bottom","value":"DAABCgABGKzn"}}
+]}]}}}}}}`
+
+func TestTimelineTweets(t *testing.T) {
+	v, err := utoolData(swagger.ResultT{Code: 1, Data: replyTimeline})
+	require.NoError(t, err)
+
+	tweets, cursor := timelineTweets(v)
+	require.Len(t, tweets, 2)
+	require.Equal(t, "DAABCgABGKzn", cursor)
+	require.Equal(t, "101", tweetId(tweets[1]))
+	require.Equal(t, "1", tweetAuthorId(tweets[1]))
+	require.Equal(t, time.Date(2024, 5, 7, 9, 0, 0, 0, time.UTC), tweetCreatedAt(tweets[1]).UTC())
+
+	_, err = utoolData(swagger.ResultT{Code: 0, Msg: "rate limit"})
+	require.True(t, isTransientError(err))
+
+	for _, msg := range []string{"Tweet not found", "apiKey is invalid", "Insufficient balance", "This account is protected"} {
+		_, err = utoolData(swagger.ResultT{Code: 0, Msg: msg})
+		require.Error(t, err, msg)
+		require.False(t, isTransientError(err), msg)
+	}
+
+	_, err = utoolData(swagger.ResultT{Code: 1, Data: `{"errors":[{"code":144,"message":"No status found with that ID."}]}`})
+	require.Error(t, err)
+	require.False(t, isTransientError(err))
+
+	_, err = utoolData(swagger.ResultT{Code: 1, Data: `{"errors":[{"code":88,"message":"Rate limit exceeded"}]}`})
+	require.True(t, isTransientError(err))
+}
+
+func TestMatchReply(t *testing.T) {
+	reply := fastjson.MustParse(`{"rest_id":"101","legacy":{"created_at":"Tue May 07 09:00:00 +0000 2024","full_text":"@Titannet_dao Great work on #Titan storage!","in_reply_to_status_id_str":"100","user_id_str":"1","entities":{"hashtags":[{"text":"Titan"}]}}}`)
+	since := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)
+
+	require.True(t, matchReply(reply, "1", &model.MissionParams{TweetID: "100"}, time.Time{}))
+	require.True(t, matchReply(reply, "1", &model.MissionParams{TweetID: "100", RequiredKeywords: []string{"great", "STORAGE"}}, since))
+	require.False(t, matchReply(reply, "1", &model.MissionParams{TweetID: "100", RequiredKeywords: []string{"node"}}, since))
+	require.False(t, matchReply(reply, "1", &model.MissionParams{TweetID: "100", RequiredHashtags: []string{"DePIN"}}, since))
+	require.False(t, matchReply(reply, "2", &model.MissionParams{TweetID: "100"}, since))
+	require.False(t, matchReply(reply, "1", &model.MissionParams{TweetID: "200"}, since))
+	require.False(t, matchReply(reply, "1", &model.MissionParams{TweetID: "100"}, since.Add(24*time.Hour)))
+}
+
+func TestParseTweetURL(t *testing.T) {
+	for _, link := range []string{
+		"https://x.com/shaqlin1/status/1783055657870213344",
+		"https://twitter.com/shaqlin1/status/1783055657870213344?s=20&t=abc",
+		"https://mobile.twitter.com/shaqlin1/status/1783055657870213344/photo/1",
+		"https://www.x.com/shaqlin1/status/1783055657870213344/",
+		"https://mobile.x.com/i/web/status/1783055657870213344",
+		"x.com/shaqlin1/statuses/1783055657870213344",
+		" https://X.com/shaqlin1/status/1783055657870213344 ",
+	} {
+		id, err := parseTweetURL(link)
+		require.NoError(t, err, link)
+		require.Equal(t, "1783055657870213344", id, link)
+	}
+
+	for _, link := range []string{
+		"",
+		"https://x.com/shaqlin1",
+		"https://x.com/shaqlin1/status/abc",
+		"https://example.com/shaqlin1/status/1783055657870213344",
+		"https://x.com.example.com/shaqlin1/status/1783055657870213344",
+	} {
+		_, err := parseTweetURL(link)
+		require.Error(t, err, link)
+	}
+}
+
+func TestMatchQuote(t *testing.T) {
+	quote := fastjson.MustParse(`{"rest_id":"102","core":{"user_results":{"result":{"rest_id":"1"}}},"quoted_status_result":{"result":{"__typename":"TweetWithVisibilityResults","tweet":{"rest_id":"100"}}},"legacy":{"created_at":"Tue May 07 09:00:00 +0000 2024","full_text":"Join Titan @a @b @c","entities":{"user_mentions":[{},{},{}]}}}`)
+	since := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)
+
+	require.True(t, matchQuote(quote, "1", &model.MissionParams{TweetID: "100", RequiredMentions: 3}, since))
+	require.False(t, matchQuote(quote, "1", &model.MissionParams{TweetID: "100", RequiredMentions: 4}, since))
+	require.False(t, matchQuote(quote, "1", &model.MissionParams{TweetID: "200"}, since))
+	require.False(t, matchQuote(quote, "2", &model.MissionParams{TweetID: "100"}, since))
+	require.False(t, matchQuote(fastjson.MustParse(`{"rest_id":"103","legacy":{"user_id_str":"1"}}`), "1", &model.MissionParams{TweetID: "100"}, time.Time{}))
+}
+
+func userPage(id, cursor string) string {
+	return fmt.Sprintf(`{"data":{"favoriters_timeline":{"timeline":{"instructions":[{"type":"TimelineAddEntries","entries":[
+{"content":{"itemContent":{"user_results":{"result":{"rest_id":%q}}}}},
+{"content":{"cursorType":"Bottom","value":%q}}]}]}}}}`, id, cursor)
+}
+
+func TestTimelineHasUser(t *testing.T) {
+	var fetched []string
+	fetch := func(cursor string) (swagger.ResultT, *http.Response, error) {
+		fetched = append(fetched, cursor)
+		return swagger.ResultT{Code: 1, Data: userPage(fmt.Sprint(len(fetched)), fmt.Sprintf("c%d", len(fetched)))}, nil, nil
+	}
+
+	found, err := timelineHasUser("3", fetch)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, []string{"", "c1", "c2"}, fetched)
+
+	fetched = nil
+	found, err = timelineHasUser("unknown", fetch)
+	require.NoError(t, err)
+	require.False(t, found)
+	require.Len(t, fetched, maxTweetUserPages)
+
+	_, err = timelineHasUser("1", func(cursor string) (swagger.ResultT, *http.Response, error) {
+		return swagger.ResultT{Code: 0, Msg: "too many requests"}, nil, nil
+	})
+	require.True(t, isTransientError(err))
+}
+
+func TestParseFriendship(t *testing.T) {
+	following, err := parseFriendship(fastjson.MustParse(`{"relationship":{"source":{"id_str":"1","following":true},"target":{"id_str":"2"}}}`))
+	require.NoError(t, err)
+	require.True(t, following)
+
+	following, err = parseFriendship(fastjson.MustParse(`{"relationship":{"source":{"id_str":"1","following":false}}}`))
+	require.NoError(t, err)
+	require.False(t, following)
+
+	_, err = parseFriendship(fastjson.MustParse(`{"errors":[]}`))
+	require.Error(t, err)
+}
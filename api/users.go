@@ -201,7 +201,7 @@ func GetNumericVerifyCodeHandler(c *gin.Context) {
 	}
 	userInfo.Username = req.Username
 	verifyType := strconv.FormatInt(req.Type, 10)
-	lang := c.GetHeader("Lang")
+	locale := requestLocale(c)
 	userInfo.UserEmail = userInfo.Username
 
 	var key string
@@ -238,7 +238,7 @@ func GetNumericVerifyCodeHandler(c *gin.Context) {
 		return
 	}
 
-	if err = sendEmail(userInfo.Username, verifyCode, lang); err != nil {
+	if err = sendEmail(userInfo.Username, verifyCode, locale); err != nil {
 		log.Errorf("send email: %v", err)
 		if strings.Contains(err.Error(), "timed out") {
 			c.JSON(http.StatusOK, respErrorCode(errors.TimeoutCode, c))
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// 任务校验器类型, 对应 mission.verifier 字段
+const (
+	VerifierFollowTwitter          = "follow_twitter"
+	VerifierRetweet                = "retweet"
+	VerifierLikeTweet              = "like_tweet"
+	VerifierQuoteTweet             = "quote_tweet"
+	VerifierPostTweet              = "post_tweet"
+	VerifierReplyTweet             = "reply_tweet"
+	VerifierHashtagTweet           = "hashtag_tweet"
+	VerifierJoinDiscord            = "join_discord"
+	VerifierJoinDiscordChannel     = "join_discord_channel"
+	VerifierInviteFriendsToDiscord = "invite_friends_to_discord"
+	VerifierJoinTelegram           = "join_telegram"
+	VerifierBindingKOL             = "binding_kol"
+	VerifierVisitOfficialWebsite   = "visit_official_website"
+	VerifierVisitReferrerPage      = "visit_referrer_page"
+	VerifierManualReview           = "manual_review"
+	VerifierQuiz                   = "quiz"
+	VerifierStarGithubRepo         = "star_github_repo"
+	VerifierForkGithubRepo         = "fork_github_repo"
+	VerifierGithubMergedPR         = "github_merged_pr"
+)
+
+// MissionVerifier checks whether the user has completed the mission and records the completion.
+type MissionVerifier interface {
+	Verify(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error
+}
+
+// MissionVerifierFunc adapts an ordinary function to the MissionVerifier interface.
+// A completion already recorded for the current period counts as verified.
+type MissionVerifierFunc func(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error
+
+func (f MissionVerifierFunc) Verify(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+	err := f(ctx, mission, username, queryOpt)
+	if errors.Is(err, dao.ErrAlreadyAwarded) {
+		return nil
+	}
+	return err
+}
+
+// errVerificationFailed 校验器确认用户未完成任务 (未关注、未加入群组等) 时返回, 与接口、数据库等无法确定结果的错误区分
+var errVerificationFailed = errors.New("verification failed")
+
+type verificationFailedError struct {
+	msg string
+}
+
+func (e *verificationFailedError) Error() string {
+	return e.msg
+}
+
+func (e *verificationFailedError) Is(target error) bool {
+	return target == errVerificationFailed
+}
+
+// verificationFailed 用户未完成任务的错误, 错误信息保持不变
+func verificationFailed(msg string) error {
+	return &verificationFailedError{msg: msg}
+}
+
+// MissionParamsValidator is implemented by verifiers that read parameters from the mission row.
+type MissionParamsValidator interface {
+	ValidateParams(mission *model.Mission, params *model.MissionParams) error
+}
+
+type paramsVerifier struct {
+	MissionVerifierFunc
+	validate func(mission *model.Mission, params *model.MissionParams) error
+}
+
+func (v paramsVerifier) ValidateParams(mission *model.Mission, params *model.MissionParams) error {
+	return v.validate(mission, params)
+}
+
+var (
+	verifiers   = make(map[string]MissionVerifier)
+	verifiersMu sync.RWMutex
+)
+
+func init() {
+	RegisterVerifier(VerifierFollowTwitter, MissionVerifierFunc(checkFollowTwitter))
+	RegisterVerifier(VerifierRetweet, paramsVerifier{checkReTweet, requireTweetId})
+	RegisterVerifier(VerifierLikeTweet, paramsVerifier{checkLikeTweet, requireTweetId})
+	RegisterVerifier(VerifierQuoteTweet, paramsVerifier{checkQuoteTweet, requireTweetId})
+	RegisterVerifier(VerifierPostTweet, paramsVerifier{checkPostTweet, requireTweetContent})
+	RegisterVerifier(VerifierReplyTweet, paramsVerifier{checkReplyTweet, requireTweetId})
+	RegisterVerifier(VerifierHashtagTweet, paramsVerifier{checkHashtagTweet, requireHashtags})
+	RegisterVerifier(VerifierJoinDiscord, MissionVerifierFunc(checkJoinDiscord))
+	RegisterVerifier(VerifierJoinDiscordChannel, paramsVerifier{checkJoinVolunteerChannel, requireTargetChannel})
+	RegisterVerifier(VerifierInviteFriendsToDiscord, MissionVerifierFunc(checkInviteFriendsToDiscord))
+	RegisterVerifier(VerifierJoinTelegram, paramsVerifier{checkJoinTelegram, requireTargetChannel})
+	RegisterVerifier(VerifierBindingKOL, MissionVerifierFunc(checkBindingKOL))
+	RegisterVerifier(VerifierVisitOfficialWebsite, MissionVerifierFunc(checkVisitOfficialWebsite))
+	RegisterVerifier(VerifierVisitReferrerPage, paramsVerifier{checkVisitReferrerPage, requireTargetURL})
+	RegisterVerifier(VerifierManualReview, MissionVerifierFunc(checkManualReview))
+	RegisterVerifier(VerifierQuiz, paramsVerifier{checkQuiz, requireQuizMaxAttempts})
+	RegisterVerifier(VerifierStarGithubRepo, paramsVerifier{checkStarGithubRepo, requireGithubRepository})
+	RegisterVerifier(VerifierForkGithubRepo, paramsVerifier{checkForkGithubRepo, requireGithubRepository})
+	RegisterVerifier(VerifierGithubMergedPR, paramsVerifier{checkGithubMergedPR, requireGithubOrganization})
+}
+
+// RegisterVerifier 注册任务校验器, 同名的校验器会被覆盖
+func RegisterVerifier(name string, v MissionVerifier) {
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+
+	verifiers[name] = v
+}
+
+// GetVerifier 根据任务配置的校验器类型获取校验器
+func GetVerifier(mission *model.Mission) (MissionVerifier, bool) {
+	verifiersMu.RLock()
+	defer verifiersMu.RUnlock()
+
+	v, ok := verifiers[mission.Verifier]
+	return v, ok
+}
+
+// isPerTweetVerifier 点赞、转推任务按推文区分完成记录, 更换推文后需要重新完成
+func isPerTweetVerifier(verifier string) bool {
+	return verifier == VerifierRetweet || verifier == VerifierLikeTweet
+}
+
+// ValidateMission 校验任务的校验器类型、重复规则与参数配置, 创建或更新任务时调用
+func ValidateMission(mission *model.Mission) error {
+	v, ok := GetVerifier(mission)
+	if !ok {
+		return fmt.Errorf("unknown verifier: %q", mission.Verifier)
+	}
+
+	if _, err := missionRecurrence(mission); err != nil {
+		return err
+	}
+
+	params, err := mission.GetParams()
+	if err != nil {
+		return fmt.Errorf("parse params: %w", err)
+	}
+
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	pv, ok := v.(MissionParamsValidator)
+	if !ok {
+		return nil
+	}
+
+	return pv.ValidateParams(mission, params)
+}
+
+func requireTweetId(mission *model.Mission, params *model.MissionParams) error {
+	if params.TweetID == "" {
+		return errors.New("missing tweet_id")
+	}
+	return nil
+}
+
+func requireTweetContent(mission *model.Mission, params *model.MissionParams) error {
+	if params.RequiredText == "" && len(params.RequiredHashtags) == 0 {
+		return errors.New("required_text or required_hashtags must be set")
+	}
+	return nil
+}
+
+func requireTargetChannel(mission *model.Mission, params *model.MissionParams) error {
+	if targetChannel(mission, params) == "" {
+		return errors.New("missing target_channel")
+	}
+	return nil
+}
+
+// targetChannel 优先使用参数中的频道id, 兼容旧数据的 target_id
+func targetChannel(mission *model.Mission, params *model.MissionParams) string {
+	if params.TargetChannel != "" {
+		return params.TargetChannel
+	}
+	return mission.TargetID
+}
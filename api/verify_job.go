@@ -0,0 +1,421 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/dao"
+	errorsx "github.com/gnasnik/titan-quest/core/errors"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/xid"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	defaultVerifyWorkers     = 8
+	defaultVerifyMaxAttempts = 3
+
+	// verifyJobTimeout 单次校验的超时时间
+	verifyJobTimeout = time.Minute
+	// verifyJobLease 执行中的作业超过该时间未更新视为 worker 异常退出, 重新放回队列
+	verifyJobLease = 5 * time.Minute
+	// verifyJobActiveExpiration 同一用户同一任务的作业去重时间
+	verifyJobActiveExpiration = 10 * time.Minute
+
+	verifyRetryBaseDelay = 5 * time.Second
+	verifyRetryMaxDelay  = 2 * time.Minute
+	verifyDequeueTimeout = 5 * time.Second
+
+	// maxVerifyJobWait 查询作业状态时最长的等待时间
+	maxVerifyJobWait = 30 * time.Second
+)
+
+// transientError 第三方接口的临时错误, 校验作业会稍后重试
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+func transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+// upstreamError 包装第三方接口返回的错误, 网络错误、限流及服务端错误视为临时错误
+func upstreamError(err error, resp *http.Response) error {
+	if resp != nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+	return transient(err)
+}
+
+// telegramError 包装 telegram 接口返回的错误, 除请求错误外均视为临时错误
+func telegramError(err error) error {
+	var te *tele.Error
+	if errors.As(err, &te) && te.Code < http.StatusInternalServerError {
+		return err
+	}
+	return transient(err)
+}
+
+// isTransientError 判断错误能否通过重试恢复
+func isTransientError(err error) bool {
+	var te *transientError
+	if errors.As(err, &te) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// verifyRetryDelay 第 attempts 次尝试失败后的重试间隔
+func verifyRetryDelay(attempts int) time.Duration {
+	delay := verifyRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= verifyRetryMaxDelay {
+			return verifyRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+func isVerifyJobFinished(job *model.VerifyJob) bool {
+	return job.Status == VerifyJobStatusSucceeded || job.Status == VerifyJobStatusFailed
+}
+
+func verifyJobQueryOption(job *model.VerifyJob) dao.QueryOption {
+	return dao.QueryOption{
+		StartTime: job.StartTime,
+		EndTime:   job.EndTime,
+		Content:   job.Content,
+	}
+}
+
+// submitVerifyJob 提交任务校验作业, 同一用户同一任务已有未结束的作业时直接返回该作业
+func submitVerifyJob(ctx context.Context, username string, mission *model.Mission, opt dao.QueryOption) (*model.VerifyJob, error) {
+	now := time.Now()
+	job := &model.VerifyJob{
+		ID:        xid.New().String(),
+		Username:  username,
+		MissionID: mission.ID,
+		StartTime: opt.StartTime,
+		EndTime:   opt.EndTime,
+		Content:   opt.Content,
+		Status:    VerifyJobStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	for {
+		ok, existing, err := dao.AcquireActiveVerifyJob(ctx, username, mission.ID, job.ID, verifyJobActiveExpiration)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			break
+		}
+
+		out, err := dao.GetVerifyJob(ctx, existing)
+		if err == nil && !isVerifyJobFinished(out) {
+			return out, nil
+		}
+
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+
+		// 已存在的作业已结束或过期, 释放后重新提交
+		if err := dao.ReleaseActiveVerifyJob(ctx, username, mission.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dao.SaveVerifyJob(ctx, job); err != nil {
+		_ = dao.ReleaseActiveVerifyJob(ctx, username, mission.ID)
+		return nil, err
+	}
+
+	if err := dao.EnqueueVerifyJob(ctx, job.ID); err != nil {
+		_ = dao.ReleaseActiveVerifyJob(ctx, username, mission.ID)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// waitVerifyJob 获取作业, wait 大于 0 时等待作业结束或超时
+func waitVerifyJob(ctx context.Context, id string, wait time.Duration) (*model.VerifyJob, error) {
+	if wait <= 0 {
+		return dao.GetVerifyJob(ctx, id)
+	}
+
+	if wait > maxVerifyJobWait {
+		wait = maxVerifyJobWait
+	}
+
+	sub := dao.SubscribeVerifyJob(ctx, id)
+	defer sub.Close()
+
+	// 确认订阅成功后再查询, 避免错过结束通知
+	if _, err := sub.Receive(ctx); err != nil {
+		return nil, err
+	}
+
+	job, err := dao.GetVerifyJob(ctx, id)
+	if err != nil || isVerifyJobFinished(job) {
+		return job, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-sub.Channel():
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return dao.GetVerifyJob(ctx, id)
+}
+
+// StartVerifyWorkers 启动任务校验作业的 worker 及重试调度
+func StartVerifyWorkers(ctx context.Context, cfg *config.Config) {
+	workers := cfg.VerifyWorkers
+	if workers <= 0 {
+		workers = defaultVerifyWorkers
+	}
+
+	for i := 0; i < workers; i++ {
+		go runVerifyWorker(ctx)
+	}
+
+	go runVerifyScheduler(ctx)
+}
+
+func verifyMaxAttempts() int {
+	if config.Cfg.VerifyMaxAttempts > 0 {
+		return config.Cfg.VerifyMaxAttempts
+	}
+	return defaultVerifyMaxAttempts
+}
+
+func runVerifyWorker(ctx context.Context) {
+	for ctx.Err() == nil {
+		id, err := dao.DequeueVerifyJob(ctx, verifyDequeueTimeout)
+		if err == redis.Nil {
+			continue
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("DequeueVerifyJob: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		processVerifyJob(ctx, id)
+	}
+}
+
+func runVerifyScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var lastReap time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := dao.PromoteDelayedVerifyJobs(ctx, now); err != nil {
+				log.Errorf("PromoteDelayedVerifyJobs: %v", err)
+			}
+
+			if now.Sub(lastReap) >= time.Minute {
+				reapVerifyJobs(ctx, now)
+				lastReap = now
+			}
+		}
+	}
+}
+
+// reapVerifyJobs 将超时未更新的执行中作业放回队列
+func reapVerifyJobs(ctx context.Context, now time.Time) {
+	ids, err := dao.GetProcessingVerifyJobs(ctx)
+	if err != nil {
+		log.Errorf("GetProcessingVerifyJobs: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		job, err := dao.GetVerifyJob(ctx, id)
+		if err == redis.Nil {
+			_ = dao.AckVerifyJob(ctx, id)
+			continue
+		}
+
+		if err != nil {
+			log.Errorf("GetVerifyJob: %v", err)
+			continue
+		}
+
+		if now.Sub(job.UpdatedAt) < verifyJobLease {
+			continue
+		}
+
+		if _, err := dao.RequeueVerifyJob(ctx, id); err != nil {
+			log.Errorf("RequeueVerifyJob: %v", err)
+			continue
+		}
+
+		log.Warnf("verify job %s lease expired, requeued", id)
+	}
+}
+
+func processVerifyJob(ctx context.Context, id string) {
+	job, err := dao.GetVerifyJob(ctx, id)
+	if err == redis.Nil {
+		_ = dao.AckVerifyJob(ctx, id)
+		return
+	}
+
+	if err != nil {
+		// 留在执行中队列, 由 reapVerifyJobs 重新放回
+		log.Errorf("GetVerifyJob: %v", err)
+		return
+	}
+
+	if isVerifyJobFinished(job) {
+		_ = dao.AckVerifyJob(ctx, id)
+		return
+	}
+
+	job.Status = VerifyJobStatusRunning
+	job.Attempts++
+	job.UpdatedAt = time.Now()
+	if err := dao.SaveVerifyJob(ctx, job); err != nil {
+		log.Errorf("SaveVerifyJob: %v", err)
+		return
+	}
+
+	code, err := executeVerifyJob(ctx, job)
+
+	switch {
+	case err == nil:
+		job.Status = VerifyJobStatusSucceeded
+	case code == 0 && isTransientError(err) && job.Attempts < verifyMaxAttempts():
+		delay := verifyRetryDelay(job.Attempts)
+		log.Warnf("verify job %s attempt %d: %v, retry in %s", job.ID, job.Attempts, err, delay)
+
+		job.Status = VerifyJobStatusRetrying
+		job.Error = err.Error()
+		job.UpdatedAt = time.Now()
+		if err := dao.SaveVerifyJob(ctx, job); err != nil {
+			log.Errorf("SaveVerifyJob: %v", err)
+		}
+
+		if err := dao.DelayVerifyJob(ctx, job.ID, time.Now().Add(delay)); err != nil {
+			log.Errorf("DelayVerifyJob: %v", err)
+		}
+		return
+	case code == 0 && isTransientError(err):
+		job.Status = VerifyJobStatusFailed
+		job.ErrCode = errorsx.TimeoutCode
+	default:
+		job.Status = VerifyJobStatusFailed
+		job.ErrCode = code
+		if code == 0 {
+			job.ErrCode = errorsx.MissionUnComplete
+		}
+	}
+
+	if err != nil {
+		log.Errorf("verify job %s mission %d: %v", job.ID, job.MissionID, err)
+		job.Error = err.Error()
+	}
+
+	finishVerifyJob(ctx, job)
+}
+
+// executeVerifyJob 执行任务校验, 校验前置条件不满足时返回对应的错误码
+func executeVerifyJob(ctx context.Context, job *model.VerifyJob) (int, error) {
+	mission, err := getMissionById(ctx, job.MissionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errorsx.NotFound, err
+	}
+
+	if err != nil {
+		return 0, transient(err)
+	}
+
+	// 排队期间任务可能已结束
+	state := missionState(mission, time.Now())
+	if code := missionStateErrorCode(state); code != 0 {
+		return code, fmt.Errorf("mission %d is %s", mission.ID, missionStatusName(state))
+	}
+
+	if mission.SoldOut(mission.Credit) {
+		return errorsx.MissionSoldOut, dao.ErrMissionSoldOut
+	}
+
+	verifier, ok := GetVerifier(mission)
+	if !ok {
+		return errorsx.NoImplement, fmt.Errorf("verifier %q not found", mission.Verifier)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, verifyJobTimeout)
+	defer cancel()
+
+	err = verifier.Verify(ctx, mission, job.Username, verifyJobQueryOption(job))
+	if errors.Is(err, dao.ErrMissionSoldOut) {
+		return errorsx.MissionSoldOut, err
+	}
+
+	return 0, err
+}
+
+func finishVerifyJob(ctx context.Context, job *model.VerifyJob) {
+	job.UpdatedAt = time.Now()
+	if err := dao.SaveVerifyJob(ctx, job); err != nil {
+		log.Errorf("SaveVerifyJob: %v", err)
+	}
+
+	if err := dao.AckVerifyJob(ctx, job.ID); err != nil {
+		log.Errorf("AckVerifyJob: %v", err)
+	}
+
+	if err := dao.ReleaseActiveVerifyJob(ctx, job.Username, job.MissionID); err != nil {
+		log.Errorf("ReleaseActiveVerifyJob: %v", err)
+	}
+
+	if err := dao.PublishVerifyJob(ctx, job.ID); err != nil {
+		log.Errorf("PublishVerifyJob: %v", err)
+	}
+}
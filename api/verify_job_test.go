@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/stretchr/testify/require"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestVerifyRetryDelay(t *testing.T) {
+	require.Equal(t, 5*time.Second, verifyRetryDelay(1))
+	require.Equal(t, 10*time.Second, verifyRetryDelay(2))
+	require.Equal(t, 20*time.Second, verifyRetryDelay(3))
+	require.Equal(t, verifyRetryMaxDelay, verifyRetryDelay(10))
+}
+
+func TestIsTransientError(t *testing.T) {
+	err := errors.New("upstream")
+
+	// 无响应说明是网络错误
+	require.True(t, isTransientError(upstreamError(err, nil)))
+	require.True(t, isTransientError(upstreamError(err, &http.Response{StatusCode: http.StatusBadGateway})))
+	require.True(t, isTransientError(upstreamError(err, &http.Response{StatusCode: http.StatusTooManyRequests})))
+	require.False(t, isTransientError(upstreamError(err, &http.Response{StatusCode: http.StatusBadRequest})))
+
+	require.True(t, isTransientError(fmt.Errorf("wrapped: %w", transient(err))))
+	require.True(t, isTransientError(context.DeadlineExceeded))
+	require.False(t, isTransientError(errors.New("user unfollow")))
+
+	require.False(t, isTransientError(telegramError(tele.ErrNotFound)))
+	require.True(t, isTransientError(telegramError(tele.ErrInternal)))
+}
+
+func TestVerifierAlreadyAwarded(t *testing.T) {
+	verify := func(err error) error {
+		return MissionVerifierFunc(func(ctx context.Context, mission *model.Mission, username string, queryOpt dao.QueryOption) error {
+			return err
+		}).Verify(context.Background(), &model.Mission{}, "user", dao.QueryOption{})
+	}
+
+	require.NoError(t, verify(dao.ErrAlreadyAwarded))
+	require.NoError(t, verify(fmt.Errorf("award: %w", dao.ErrAlreadyAwarded)))
+	require.ErrorIs(t, verify(dao.ErrMissionSoldOut), dao.ErrMissionSoldOut)
+}
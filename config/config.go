@@ -3,38 +3,87 @@ package config
 var Cfg Config
 
 type Config struct {
-	Mode                     string
-	ApiListen                string
-	DatabaseURL              string
-	SecretKey                string
-	RedisAddr                string
-	RedisPassword            string
-	Emails                   []EmailConfig
-	UToolAPIKeys             []string
-	TwitterAPIKey            string
-	TwitterAPIKeySecret      string
-	DiscordClientId          string
-	DiscordClientSecret      string
-	OfficialTwitterUserId    int64
-	OfficialTelegramGroupId  int64
-	DiscordBotToken          string
-	TelegramBotTestToken     string
-	TelegramBotSparkToken    string
-	TelegramBotID            string
-	TelegramCallback         string
-	RedirectURI              string
-	DisableDiscordBot        bool
-	BrowsOfficialWebsiteTime int64  // 浏览官网的时间
-	OfficialWebsiteURI       string // 官网地址
-	AesKey                   string
-	InviteShareRate          int64 // 邀请比例分成
-
-	TitanAPI TitanAPIConfig
+	Mode                          string
+	ApiListen                     string
+	DatabaseURL                   string
+	SecretKey                     string
+	RedisAddr                     string
+	RedisPassword                 string
+	Emails                        []EmailConfig
+	UToolAPIKeys                  []string
+	TwitterAPIKey                 string
+	TwitterAPIKeySecret           string
+	DiscordClientId               string
+	DiscordClientSecret           string
+	GithubClientId                string
+	GithubClientSecret            string
+	GithubAPIURL                  string // GitHub 接口地址, 默认 https://api.github.com
+	OfficialTwitterUserId         int64
+	OfficialTelegramGroupId       int64
+	DiscordBotToken               string
+	TelegramBotTestToken          string
+	TelegramBotSparkToken         string
+	TelegramBotID                 string
+	TelegramCallback              string
+	RedirectURI                   string
+	TrustedProxies                []string // 反向代理的地址或网段, 只信任这些代理传入的 X-Forwarded-For, 未配置时使用连接的地址
+	DisableDiscordBot             bool
+	BrowsOfficialWebsiteTime      int64  // 浏览官网的时间, 单位秒
+	BrowsOfficialWebsiteHeartbeat int64  // 浏览官网的心跳间隔, 单位秒, 默认 10
+	OfficialWebsiteURI            string // 官网地址
+	TrackingLinkURI               string // 追踪链接地址, 如 https://quest.titannet.io/api/v1/track, 未配置时使用请求的地址
+	InviteShareRate               int64  // 邀请比例分成
+	VerifyWorkers                 int    // 任务校验作业的并发数
+	VerifyMaxAttempts             int    // 任务校验遇到临时错误时的最大尝试次数
+	MissionCacheTTL               int64  // 进程内任务目录的最长缓存时间, 单位秒
+
+	TitanAPI      TitanAPIConfig
+	Reverify      ReverifyConfig
+	HashtagSearch HashtagSearchConfig
+	Streak        StreakConfig
+	I18n          I18nConfig
 
 	GoogleDoc    GoogleDocConfig
 	ResourcePath string
 }
 
+// ReverifyConfig 已完成任务的定期重新校验配置
+type ReverifyConfig struct {
+	Enable          bool
+	Interval        int64  // 每轮校验的间隔, 单位分钟
+	RecheckInterval int64  // 同一条完成记录两次校验的最小间隔, 单位小时
+	SampleSize      int    // 每轮最多校验的完成记录数
+	UToolSampleSize int    // 每轮最多调用 UTool 接口的校验数, 控制 UTool key 的消耗
+	GracePeriod     int64  // 校验失败后的宽限时间, 单位小时
+	Action          string // 宽限期后仍未通过时的处理方式: revoke 撤销积分, flag 仅标记等待人工处理
+}
+
+// HashtagSearchConfig 话题标签任务的定时搜索配置
+type HashtagSearchConfig struct {
+	Enable   bool
+	Interval int64 // 每轮搜索的间隔, 单位分钟
+	MaxPages int   // 每个任务每轮最多搜索的页数, 控制 UTool key 的消耗
+}
+
+// I18nConfig 多语言配置
+type I18nConfig struct {
+	DefaultLocale string // 无法匹配请求语言时使用的语言, 默认 en
+	TemplateDir   string // 邮件模板目录, 目录下按语言存放 <locale>/mail.html, 未找到时使用内置模板
+	SyncInterval  int64  // 重新加载文案表的间隔, 单位分钟
+}
+
+// StreakConfig 每日任务连续完成的奖励配置
+type StreakConfig struct {
+	Timezone string // 计算连续天数及任务周期使用的时区, 如 Asia/Shanghai, 默认使用服务器时区
+	Bonus    []StreakBonusConfig
+}
+
+// StreakBonusConfig 连续完成 Days 天后额外奖励 Percent% 的积分, 取达到的最高档
+type StreakBonusConfig struct {
+	Days    int64
+	Percent int64
+}
+
 type TitanAPIConfig struct {
 	BasePath string
 	Key      string
@@ -5,16 +5,24 @@ import (
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gnasnik/titan-quest/core/dao"
+	"github.com/go-redis/redis/v9"
 	"github.com/golang-module/carbon/v2"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
-	inviteCounterKey = "gm::discord::invitecounter::%s::%s"
+	inviteCounterKey = "gm::discord::invitecounter::"
+	inviteRecordsKey = "gm::discord::invites::%s"
 	recordsKey       = "gm::discord::inviterecords"
 	membersKey       = "gm::discord::members"
 )
 
+// inviteRecordsRetention 邀请记录保留的时间, 需要覆盖最长的任务周期
+const inviteRecordsRetention = 90 * 24 * time.Hour
+
 func NewBot(token string) (*discordgo.Session, error) {
 	s, err := discordgo.New("Bot " + token)
 	if err != nil {
@@ -27,6 +35,8 @@ func RunDiscordBot(s *discordgo.Session) {
 
 	ctx := context.Background()
 
+	migrateInviteCounters(ctx)
+
 	invitesBefore := make(map[string][]*discordgo.Invite)
 
 	s.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
@@ -105,10 +115,9 @@ func RunDiscordBot(s *discordgo.Session) {
 					continue
 				}
 
-				startOfWeek := carbon.Now().StartOfWeek().String()
-				_, err = dao.RedisCache.Incr(ctx, fmt.Sprintf(inviteCounterKey, newInvite.Inviter.ID, startOfWeek)).Result()
+				err = addInviteRecord(ctx, newInvite.Inviter.ID, m.User.ID, time.Now())
 				if err != nil {
-					log.Printf("redis incr: %v\n", err)
+					log.Printf("add invite record: %v\n", err)
 				}
 
 				return
@@ -135,6 +144,64 @@ func RunDiscordBot(s *discordgo.Session) {
 	defer s.Close()
 }
 
+// addInviteRecord 按邀请时间记录被邀请人, 由任务的周期规则统计邀请人数.
+// 重新加入的用户不更新邀请时间, 超过保留时间的记录会被清理
+func addInviteRecord(ctx context.Context, inviterId, memberId string, now time.Time) error {
+	key := fmt.Sprintf(inviteRecordsKey, inviterId)
+	expired := now.Add(-inviteRecordsRetention).Unix()
+
+	pipe := dao.RedisCache.TxPipeline()
+	pipe.ZAddNX(ctx, key, redis.Z{Score: float64(now.Unix()), Member: memberId})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(expired, 10))
+	pipe.Expire(ctx, key, inviteRecordsRetention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// migrateInviteCounters 将旧的每周邀请计数迁移为邀请记录并删除旧的计数.
+// 只有本周的计数仍然有效, 计数对应的被邀请人未知, 使用占位成员记录为当前时间的邀请, 重复迁移不会重复计数
+func migrateInviteCounters(ctx context.Context) {
+	now := time.Now()
+	week := carbon.Now().StartOfWeek().String()
+
+	iter := dao.RedisCache.Scan(ctx, 0, inviteCounterKey+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		inviterId, startOfWeek, ok := strings.Cut(strings.TrimPrefix(key, inviteCounterKey), "::")
+		if ok && startOfWeek == week {
+			if err := migrateInviteCounter(ctx, key, inviterId, startOfWeek, now); err != nil {
+				log.Printf("migrate invite counter %s: %v\n", key, err)
+				continue
+			}
+		}
+
+		if err := dao.RedisCache.Del(ctx, key).Err(); err != nil {
+			log.Printf("redis del: %s %v\n", key, err)
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		log.Printf("scan invite counters: %v\n", err)
+	}
+}
+
+func migrateInviteCounter(ctx context.Context, key, inviterId, startOfWeek string, now time.Time) error {
+	count, err := dao.RedisCache.Get(ctx, key).Int64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	for i := int64(0); i < count; i++ {
+		member := fmt.Sprintf("legacy::%s::%d", startOfWeek, i)
+		if err := addInviteRecord(ctx, inviterId, member, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func findInviteByCode(invites []*discordgo.Invite, code string) *discordgo.Invite {
 	for _, invite := range invites {
 		if invite.Code == code {
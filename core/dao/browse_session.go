@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+const browseSessionKey = "TITAN::QUEST::BROWSE::SESSION::%s"
+
+// browseHeartbeatScript 累计浏览时长, 两次心跳的间隔超过 maxGap 时只计 maxGap, 页面不可见时不计时长.
+// 时长达到要求后会话标记为已完成, 不再累计时长, 发放积分成功后再删除会话, 发放失败时下次心跳可以重试
+var browseHeartbeatScript = redis.NewScript(`
+local key = KEYS[1]
+local maxGap = tonumber(ARGV[1])
+local required = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local active = ARGV[4] == "1"
+
+if redis.call("EXISTS", key) == 0 then
+	return false
+end
+
+local username = redis.call("HGET", key, "username")
+if redis.call("HGET", key, "completed") == "1" then
+	redis.call("EXPIRE", key, ttl)
+	return {username, tonumber(redis.call("HGET", key, "dwell")), 1}
+end
+
+local now = tonumber(redis.call("TIME")[1])
+local last = tonumber(redis.call("HGET", key, "last"))
+local dwell = tonumber(redis.call("HGET", key, "dwell"))
+
+local delta = now - last
+if active and delta > 0 then
+	dwell = dwell + math.min(delta, maxGap)
+end
+
+local completed = 0
+if dwell >= required then
+	completed = 1
+end
+
+redis.call("HSET", key, "last", now, "dwell", dwell, "completed", completed)
+redis.call("EXPIRE", key, ttl)
+return {username, dwell, completed}
+`)
+
+// BrowseHeartbeat 一次心跳的结果
+type BrowseHeartbeat struct {
+	Username  string
+	Dwell     int64
+	Completed bool
+}
+
+// CreateBrowseSession 创建浏览官网的会话, ttl 内没有心跳时会话过期
+func CreateBrowseSession(ctx context.Context, id, username string, ttl time.Duration) error {
+	key := fmt.Sprintf(browseSessionKey, id)
+	_, err := RedisCache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "username", username, "last", time.Now().Unix(), "dwell", 0)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// HeartbeatBrowseSession 记录浏览官网的心跳, 累计时长达到 required 秒后返回已完成, 会话不存在时返回 redis.Nil
+func HeartbeatBrowseSession(ctx context.Context, id string, maxGap, required int64, ttl time.Duration, active bool) (*BrowseHeartbeat, error) {
+	activeArg := "0"
+	if active {
+		activeArg = "1"
+	}
+
+	values, err := browseHeartbeatScript.Run(ctx, RedisCache, []string{fmt.Sprintf(browseSessionKey, id)},
+		maxGap, required, int64(ttl/time.Second), activeArg).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected heartbeat result: %v", values)
+	}
+
+	username, _ := values[0].(string)
+	dwell, _ := values[1].(int64)
+	completed, _ := values[2].(int64)
+
+	return &BrowseHeartbeat{Username: username, Dwell: dwell, Completed: completed == 1}, nil
+}
+
+// DeleteBrowseSession 删除浏览官网的会话, 发放积分成功后调用, 保证一个会话只能完成一次
+func DeleteBrowseSession(ctx context.Context, id string) error {
+	return RedisCache.Del(ctx, fmt.Sprintf(browseSessionKey, id)).Err()
+}
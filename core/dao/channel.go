@@ -0,0 +1,33 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// ChannelTable 渠道表, 修改后同样通过任务修改通知刷新
+const ChannelTable = "channel"
+
+// GetChannels 按排序获取所有渠道
+func GetChannels(ctx context.Context) ([]*model.Channel, error) {
+	var out []*model.Channel
+	err := DB.SelectContext(ctx, &out, `select * from channel order by sort_id, id`)
+	return out, err
+}
+
+// SaveChannel 按 code 新增或修改渠道
+func SaveChannel(ctx context.Context, ch *model.Channel) error {
+	query := `insert into channel(code, group_name, title, title_cn, icon, sort_id, created_at, updated_at)
+		values(:code, :group_name, :title, :title_cn, :icon, :sort_id, now(), now())
+		on duplicate key update group_name = values(group_name), title = values(title), title_cn = values(title_cn),
+		icon = values(icon), sort_id = values(sort_id), updated_at = now()`
+
+	_, err := DB.NamedExecContext(ctx, query, ch)
+	if err != nil {
+		return err
+	}
+
+	notifyMissionChanged(ctx, ChannelTable)
+	return nil
+}
@@ -27,6 +27,9 @@ const (
 	connMaxIdleTime    = 20
 )
 
+// mysqlErrDupEntry 违反唯一索引的错误码
+const mysqlErrDupEntry = 1062
+
 var ErrNoRow = fmt.Errorf("no matching row found")
 
 func Init(cfg *config.Config) error {
@@ -69,4 +72,5 @@ type QueryOption struct {
 	UserID     string         `json:"user_id"`
 	Content    string         `json:"content"`
 	Lang       model.Language `json:"lang"`
+	SeasonID   int64          `json:"season_id"` // 大于 0 时只查询该赛季及不属于任何赛季的记录
 }
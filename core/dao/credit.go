@@ -0,0 +1,236 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// kolShareRate KOL 分成比例, 百分比
+const kolShareRate = 20
+
+var (
+	ErrCreditEntryRevoked      = errors.New("credit entry has been revoked")
+	ErrCreditEntryNotRevocable = errors.New("credit entry is not revocable")
+	ErrCreditAdjustmentExists  = errors.New("credit adjustment already exists")
+)
+
+// creditBucket 流水计入的余额分类, 撤销流水计入被撤销流水的分类
+func creditBucket(entryType string) string {
+	switch entryType {
+	case model.CreditEntryInviteCommission:
+		return "invite_credits"
+	case model.CreditEntryKOLCommission:
+		return "kol_credits"
+	default:
+		return "mission_credits"
+	}
+}
+
+// appendCreditEntry 追加一条积分流水并更新余额, 必须在事务中执行.
+// 同一账户同一类型同一关联记录只能写入一次, 重复写入时返回 false
+func appendCreditEntry(ctx context.Context, tx *sqlx.Tx, entry *model.CreditLedger, bucket string) (bool, error) {
+	// 先锁定余额行, 保证流水中的余额按顺序递增
+	_, err := tx.ExecContext(ctx, `insert into credit_balance(username, updated_at) values(?, now()) on duplicate key update username = username`, entry.Username)
+	if err != nil {
+		return false, err
+	}
+
+	var balance int64
+	err = tx.GetContext(ctx, &balance, `select balance from credit_balance where username = ? for update`, entry.Username)
+	if err != nil {
+		return false, err
+	}
+
+	entry.Balance = balance + entry.Amount
+	res, err := tx.NamedExecContext(ctx, `insert into credit_ledger(username, type, ref_id, amount, balance, reason, operator, created_at)
+		values(:username, :type, :ref_id, :amount, :balance, :reason, :operator, :created_at)`, entry)
+	if isDuplicateEntry(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	entry.ID, err = res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf(`update credit_balance set balance = balance + ?, %[1]s = %[1]s + ?, updated_at = now() where username = ?`, bucket)
+	_, err = tx.ExecContext(ctx, query, entry.Amount, entry.Amount, entry.Username)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// addKOLCommission 为绑定了 KOL 邀请码的用户的任务完成记录增加 KOL 分成, 赛季任务的分成同时计入赛季的分成积分
+func addKOLCommission(ctx context.Context, tx *sqlx.Tx, kolUserId string, um *model.UserMission) error {
+	credit := um.Credit * kolShareRate / 100
+	if credit == 0 {
+		return nil
+	}
+
+	if um.SeasonID > 0 {
+		if err := addSeasonCredits(ctx, tx, um.SeasonID, kolUserId, 0, credit); err != nil {
+			return err
+		}
+	}
+
+	_, err := appendCreditEntry(ctx, tx, &model.CreditLedger{
+		Username:  kolUserId,
+		Type:      model.CreditEntryKOLCommission,
+		RefID:     strconv.FormatInt(um.ID, 10),
+		Amount:    credit,
+		Reason:    fmt.Sprintf("%s completed mission %d", um.Username, um.MissionID),
+		CreatedAt: um.CreatedAt,
+	}, creditBucket(model.CreditEntryKOLCommission))
+	return err
+}
+
+// AdjustUserCredits 管理员调整用户积分, refId 用于防止重复提交
+func AdjustUserCredits(ctx context.Context, username string, amount int64, refId, reason, operator string) (*model.CreditLedger, error) {
+	if amount == 0 {
+		return nil, errors.New("amount must not be zero")
+	}
+
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry := &model.CreditLedger{
+		Username:  username,
+		Type:      model.CreditEntryAdminAdjustment,
+		RefID:     refId,
+		Amount:    amount,
+		Reason:    reason,
+		Operator:  operator,
+		CreatedAt: time.Now(),
+	}
+
+	ok, err := appendCreditEntry(ctx, tx, entry, creditBucket(entry.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrCreditAdjustmentExists
+	}
+
+	return entry, tx.Commit()
+}
+
+// RevokeCreditEntry 撤销一条积分流水, 追加一条金额相反的撤销流水
+func RevokeCreditEntry(ctx context.Context, entryId int64, reason, operator string) (*model.CreditLedger, error) {
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var origin model.CreditLedger
+	err = tx.GetContext(ctx, &origin, `select * from credit_ledger where id = ?`, entryId)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := revokeCreditEntry(ctx, tx, &origin, reason, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, tx.Commit()
+}
+
+func revokeCreditEntry(ctx context.Context, tx *sqlx.Tx, origin *model.CreditLedger, reason, operator string) (*model.CreditLedger, error) {
+	if origin.Type == model.CreditEntryRevocation {
+		return nil, ErrCreditEntryNotRevocable
+	}
+
+	entry := &model.CreditLedger{
+		Username:  origin.Username,
+		Type:      model.CreditEntryRevocation,
+		RefID:     strconv.FormatInt(origin.ID, 10),
+		Amount:    -origin.Amount,
+		Reason:    reason,
+		Operator:  operator,
+		CreatedAt: time.Now(),
+	}
+
+	ok, err := appendCreditEntry(ctx, tx, entry, creditBucket(origin.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrCreditEntryRevoked
+	}
+
+	return entry, nil
+}
+
+// GetCreditBalance 获取积分余额, 没有流水的账户返回零余额
+func GetCreditBalance(ctx context.Context, username string) (*model.CreditBalance, error) {
+	var out model.CreditBalance
+	err := DB.GetContext(ctx, &out, `select * from credit_balance where username = ?`, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.CreditBalance{Username: username}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// GetCreditLedger 获取积分流水
+func GetCreditLedger(ctx context.Context, username string, option QueryOption) ([]*model.CreditLedger, int64, error) {
+	var (
+		limit, offset int
+		total         int64
+		out           []*model.CreditLedger
+	)
+
+	if option.PageSize <= 0 {
+		limit = 50
+	} else {
+		limit = option.PageSize
+	}
+	if option.Page > 0 {
+		offset = limit * (option.Page - 1)
+	}
+
+	query, args, err := squirrel.Select("COUNT(id)").From("credit_ledger").Where("username = ?", username).ToSql()
+	if err != nil {
+		return nil, 0, fmt.Errorf("generate sql error:%w", err)
+	}
+	err = DB.GetContext(ctx, &total, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get total of credit_ledger error:%w", err)
+	}
+
+	query, args, err = squirrel.Select("*").From("credit_ledger").Where("username = ?", username).
+		OrderBy("id DESC").Limit(uint64(limit)).Offset(uint64(offset)).ToSql()
+	if err != nil {
+		return nil, 0, fmt.Errorf("generate sql error:%w", err)
+	}
+	err = DB.SelectContext(ctx, &out, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get list of credit_ledger error:%w", err)
+	}
+
+	return out, total, nil
+}
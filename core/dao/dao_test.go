@@ -3,9 +3,14 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gnasnik/titan-quest/config"
 	"github.com/gnasnik/titan-quest/core/generated/model"
 	"github.com/jmoiron/sqlx"
 )
@@ -119,3 +124,319 @@ func TestAddUserMissionAndInviteLog(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestAddUserMissionAndInviteLogConcurrent(t *testing.T) {
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	inviter := &model.UsersExt{Username: "inviter-" + suffix, InviteCode: "code-" + suffix}
+	invitee := &model.UsersExt{Username: "invitee-" + suffix, InviteCode: "code2-" + suffix, InvitedCode: inviter.InviteCode}
+	for _, ue := range []*model.UsersExt{inviter, invitee} {
+		if err := CreateUserExt(ctx, ue); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	award := func(period string) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- AddUserMissionAndInviteLog(ctx, &model.UserMission{
+					Username:  invitee.Username,
+					MissionID: 1002,
+					Type:      2,
+					Credit:    100,
+					Period:    period,
+					CreatedAt: time.Now(),
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil && err != ErrAlreadyAwarded {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	award("2024-05-15 00:00:00")
+	award("2024-05-15 00:00:00")
+	// 下一个周期可以再次完成
+	award("2024-05-16 00:00:00")
+
+	ums, err := GetUserMissionByMissionId(ctx, invitee.Username, 1002, QueryOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ums) != 2 {
+		t.Fatalf("expected 2 user missions, got %d", len(ums))
+	}
+
+	var inviteLogs int
+	err = DB.GetContext(ctx, &inviteLogs, `select count(*) from invite_log where invited_name = ?`, invitee.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inviteLogs != 2 {
+		t.Fatalf("expected 2 invite logs, got %d", inviteLogs)
+	}
+
+	balance, err := GetCreditBalance(ctx, invitee.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance.Balance != 200 || balance.MissionCredits != 200 {
+		t.Fatalf("expected balance 200, got %d", balance.Balance)
+	}
+}
+
+func TestMissionCapacity(t *testing.T) {
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	res, err := DB.ExecContext(ctx, `insert into mission(title, credit, status, open_url, type, max_completions, created_at, updated_at)
+		values(?, 10, 1, '', 1, 5, now(), now())`, "capacity-"+suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missionId, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- AddUserMissionAndInviteLog(ctx, &model.UserMission{
+				Username:  "capacity-" + suffix + "-" + strconv.Itoa(i),
+				MissionID: missionId,
+				Type:      1,
+				Credit:    10,
+				CreatedAt: time.Now(),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	var awarded, soldOut int
+	for err := range errs {
+		switch err {
+		case nil:
+			awarded++
+		case ErrMissionSoldOut:
+			soldOut++
+		default:
+			t.Fatal(err)
+		}
+	}
+
+	if awarded != 5 || soldOut != 15 {
+		t.Fatalf("expected 5 awarded and 15 sold out, got %d and %d", awarded, soldOut)
+	}
+
+	mission, err := GetMissionById2(ctx, missionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mission.Completions != 5 || mission.CreditsAwarded != 50 || !mission.SoldOut(10) {
+		t.Fatalf("unexpected mission capacity %+v", mission)
+	}
+}
+
+func TestSubMissionCapacity(t *testing.T) {
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	res, err := DB.ExecContext(ctx, `insert into mission(title, credit, status, open_url, type, created_at, updated_at)
+		values(?, 0, 1, '', 1, now(), now())`, "sub-capacity-"+suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missionId, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = DB.ExecContext(ctx, `insert into sub_mission(title, credit, status, open_url, type, parent_id, max_completions, created_at, updated_at)
+		values(?, 10, 1, '', 1, ?, 1, now(), now())`, "sub-capacity-"+suffix, missionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subMissionId, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []error{nil, ErrMissionSoldOut} {
+		err := AddUserMissionAndInviteLog(ctx, &model.UserMission{
+			Username:     "sub-capacity-" + suffix + "-" + strconv.Itoa(i),
+			MissionID:    missionId,
+			SubMissionID: subMissionId,
+			Type:         1,
+			Credit:       10,
+			CreatedAt:    time.Now(),
+		})
+		if err != expected {
+			t.Fatalf("expected %v, got %v", expected, err)
+		}
+	}
+
+	// 子任务的完成记录不计入父任务, 未设置上限的父任务不统计
+	mission, err := GetMissionById2(ctx, missionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mission.Completions != 0 {
+		t.Fatalf("unexpected parent mission completions %d", mission.Completions)
+	}
+}
+
+func TestDailyStreak(t *testing.T) {
+	ctx := context.Background()
+	username := "streak-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	old := config.Cfg.Streak
+	defer func() { config.Cfg.Streak = old }()
+	config.Cfg.Streak = config.StreakConfig{Timezone: "UTC", Bonus: []config.StreakBonusConfig{{Days: 2, Percent: 50}}}
+
+	day := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		createdAt := day.AddDate(0, 0, i)
+		err := AddUserMissionAndInviteLog(ctx, &model.UserMission{
+			Username:  username,
+			MissionID: 1101,
+			Type:      missionTypeDaily,
+			Credit:    100,
+			Period:    createdAt.Format("2006-01-02 00:00:00"),
+			CreatedAt: createdAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	streaks, err := GetUserStreaks(ctx, username)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streaks) != 1 || streaks[0].CurrentStreak != 3 || streaks[0].LastDay != "2024-05-17" {
+		t.Fatalf("unexpected streaks %+v", streaks)
+	}
+
+	balance, err := GetCreditBalance(ctx, username)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第 2、3 天各奖励 50%
+	if balance.Balance != 400 {
+		t.Fatalf("expected balance 400, got %d", balance.Balance)
+	}
+}
+
+func TestCreditLedger(t *testing.T) {
+	ctx := context.Background()
+	username := "ledger-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	entry, err := AdjustUserCredits(ctx, username, 50, "adjust-"+username, "compensation", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Balance != 50 {
+		t.Fatalf("expected balance 50, got %d", entry.Balance)
+	}
+
+	// 同一调整不能重复提交
+	if _, err := AdjustUserCredits(ctx, username, 50, "adjust-"+username, "compensation", "admin"); err != ErrCreditAdjustmentExists {
+		t.Fatalf("expected ErrCreditAdjustmentExists, got %v", err)
+	}
+
+	revocation, err := RevokeCreditEntry(ctx, entry.ID, "mistake", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if revocation.Amount != -50 || revocation.Balance != 0 {
+		t.Fatalf("unexpected revocation %+v", revocation)
+	}
+
+	if _, err := RevokeCreditEntry(ctx, entry.ID, "mistake", "admin"); err != ErrCreditEntryRevoked {
+		t.Fatalf("expected ErrCreditEntryRevoked, got %v", err)
+	}
+
+	if _, err := RevokeCreditEntry(ctx, revocation.ID, "mistake", "admin"); err != ErrCreditEntryNotRevocable {
+		t.Fatalf("expected ErrCreditEntryNotRevocable, got %v", err)
+	}
+
+	entries, total, err := GetCreditLedger(ctx, username, QueryOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 2 || len(entries) != 2 {
+		t.Fatalf("expected 2 ledger entries, got %d", total)
+	}
+
+	balance, err := GetCreditBalance(ctx, username)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance.Balance != 0 || balance.MissionCredits != 0 {
+		t.Fatalf("expected balance 0, got %d", balance.Balance)
+	}
+}
+
+func TestApproveMissionSubmission(t *testing.T) {
+	ctx := context.Background()
+	username := "review-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	s := &model.MissionSubmission{Username: username, MissionID: 1201, Urls: json.RawMessage(`[]`)}
+	if err := AddMissionSubmission(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	um := &model.UserMission{Username: username, MissionID: 1201, Type: 1, Credit: 100, Content: strconv.FormatInt(s.ID, 10), CreatedAt: time.Now()}
+	if err := ApproveMissionSubmission(ctx, s, um); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已审核通过的提交不能再拒绝
+	rejected := *s
+	rejected.Status = SubmissionStatusRejected
+	if err := ReviewMissionSubmission(ctx, &rejected); !errors.Is(err, ErrNoRow) {
+		t.Fatalf("expected ErrNoRow, got %v", err)
+	}
+
+	if err := ApproveMissionSubmission(ctx, s, um); !errors.Is(err, ErrNoRow) {
+		t.Fatalf("expected ErrNoRow, got %v", err)
+	}
+
+	out, err := GetMissionSubmission(ctx, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Status != SubmissionStatusApproved || out.UserMissionID != um.ID || um.ID == 0 {
+		t.Fatalf("unexpected submission %+v", out)
+	}
+}
@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+func AddGithubOAuth(ctx context.Context, oauth *model.GithubOauth) error {
+	query := `insert into github_oauth(username, state, redirect_uri, created_at, updated_at) values(:username, :state, :redirect_uri, now(), now())`
+
+	_, err := DB.NamedExecContext(ctx, query, oauth)
+	return err
+}
+
+// GetGithubOAuthByUsername 获取用户已绑定的 GitHub 账号
+func GetGithubOAuthByUsername(ctx context.Context, username string) (*model.GithubOauth, error) {
+	var out model.GithubOauth
+	err := DB.GetContext(ctx, &out, `select * from github_oauth where username = ? and github_user_id <> '' order by updated_at desc limit 1`, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func GetGithubOAuthByState(ctx context.Context, state string) (*model.GithubOauth, error) {
+	var out model.GithubOauth
+	err := DB.GetContext(ctx, &out, `select * from github_oauth where state = ? order by created_at desc limit 1`, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// GetGithubOAuth 获取绑定了该 GitHub 账号的记录
+func GetGithubOAuth(ctx context.Context, githubUserId string) (*model.GithubOauth, error) {
+	var out model.GithubOauth
+	err := DB.GetContext(ctx, &out, `select * from github_oauth where github_user_id = ? and username <> '' limit 1`, githubUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func UpdateGithubUserInfo(ctx context.Context, state string, githubUserId, githubLogin, accessToken string) error {
+	query := `update github_oauth set github_user_id = ?, github_login = ?, access_token = ?, updated_at = now() where state = ?`
+	_, err := DB.ExecContext(ctx, query, githubUserId, githubLogin, accessToken, state)
+	return err
+}
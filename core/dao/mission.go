@@ -4,16 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/gnasnik/titan-quest/config"
 	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrMissionSoldOut 任务完成人次或积分预算已用完
+var ErrMissionSoldOut = errors.New("mission sold out")
+
+// ErrAlreadyAwarded 用户在当前周期已完成该任务, 没有重复发放积分
+var ErrAlreadyAwarded = errors.New("mission already awarded in this period")
+
 func AddTwitterOAuth(ctx context.Context, oauth *model.TwitterOauth) error {
 	query := `insert into twitter_oauth(username, request_token, redirect_uri, created_at) values(:username, :request_token, :redirect_uri, now())`
 
@@ -155,6 +164,27 @@ func GetMissions(ctx context.Context) ([]*model.Mission, error) {
 	return out, nil
 }
 
+// GetMissionsByStatus 获取指定状态的任务
+func GetMissionsByStatus(ctx context.Context, statuses []int32) ([]*model.Mission, error) {
+	query, args, err := sqlx.In(`select * from mission where status in (?) order by sort_id`, statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*model.Mission
+	err = DB.SelectContext(ctx, &out, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// UpdateMissionStatus 修改任务状态, 任务当前状态不是 from 时不做修改并返回 false
+func UpdateMissionStatus(ctx context.Context, missionId int64, from, to int32) (bool, error) {
+	return UpdateMissionStatusIn(ctx, MissionTable, missionId, from, to)
+}
+
 func GetSubMissions(ctx context.Context, parentId int64) ([]*model.Mission, error) {
 	query := `select * from sub_mission where status = 1 and parent_id = ? order by id`
 
@@ -191,6 +221,32 @@ func GetMissionById2(ctx context.Context, missionId int64) (*model.Mission, erro
 	return &out, nil
 }
 
+// GetMissionPrerequisites 获取所有任务的前置任务关系
+func GetMissionPrerequisites(ctx context.Context) ([]*model.MissionPrerequisite, error) {
+	query := `select * from mission_prerequisite order by mission_id, prerequisite_id`
+
+	var out []*model.MissionPrerequisite
+	err := DB.SelectContext(ctx, &out, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetUserCompletedMissionIds 获取用户完成过的任务id
+func GetUserCompletedMissionIds(ctx context.Context, username string) ([]int64, error) {
+	query := `select distinct mission_id from user_mission where username = ?`
+
+	var out []int64
+	err := DB.SelectContext(ctx, &out, query, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
 func GetUserMissionByMissionId(ctx context.Context, username string, missionId int64, opt QueryOption) ([]*model.UserMission, error) {
 	args := []interface{}{username, missionId}
 	var where = ` where username = ? and mission_id = ?`
@@ -200,6 +256,11 @@ func GetUserMissionByMissionId(ctx context.Context, username string, missionId i
 		args = append(args, opt.StartTime)
 	}
 
+	if opt.EndTime != "" {
+		where += ` and created_at < ?`
+		args = append(args, opt.EndTime)
+	}
+
 	if opt.Content != "" {
 		where += ` and content = ?`
 		args = append(args, opt.Content)
@@ -225,6 +286,11 @@ func GetUserMissionByUser(ctx context.Context, username string, t int32, opt Que
 		args = append(args, opt.StartTime)
 	}
 
+	if opt.EndTime != "" {
+		where += ` and created_at < ?`
+		args = append(args, opt.EndTime)
+	}
+
 	query := `select * from user_mission` + where
 
 	var out []*model.UserMission
@@ -236,23 +302,17 @@ func GetUserMissionByUser(ctx context.Context, username string, t int32, opt Que
 	return out, nil
 }
 
-func SumUserCredits(ctx context.Context, username string) (int64, error) {
-	query := `select ifnull(sum(credit),0) from user_mission where username = ?`
+// GetUserMissions 获取用户所有的任务完成记录
+func GetUserMissions(ctx context.Context, username string) ([]*model.UserMission, error) {
+	query := `select * from user_mission where username = ?`
 
-	var credits int64
-	err := DB.GetContext(ctx, &credits, query, username)
+	var out []*model.UserMission
+	err := DB.SelectContext(ctx, &out, query, username)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return credits, nil
-}
-
-func AddUserMission(ctx context.Context, um *model.UserMission) error {
-	query := `insert into user_mission(username, mission_id, sub_mission_id, type, credit, content, created_at) values(:username, :mission_id, :sub_mission_id, :type, :credit, :content, :created_at)`
-
-	_, err := DB.NamedExecContext(ctx, query, &um)
-	return err
+	return out, nil
 }
 
 func AddUserTwitterLink(ctx context.Context, link *model.UserTwitterLink) error {
@@ -262,11 +322,24 @@ func AddUserTwitterLink(ctx context.Context, link *model.UserTwitterLink) error
 	return err
 }
 
-func GetUserTwitterLink(ctx context.Context, username string, missionId int64, startTime string) (*model.UserTwitterLink, error) {
-	query := `select * from user_twitter_link where username = ? and mission_id = ? and created_at >= ? order by created_at desc;`
+func GetUserTwitterLink(ctx context.Context, username string, missionId int64, opt QueryOption) (*model.UserTwitterLink, error) {
+	args := []interface{}{username, missionId}
+	var where = ` where username = ? and mission_id = ?`
+
+	if opt.StartTime != "" {
+		where += ` and created_at >= ?`
+		args = append(args, opt.StartTime)
+	}
+
+	if opt.EndTime != "" {
+		where += ` and created_at < ?`
+		args = append(args, opt.EndTime)
+	}
+
+	query := `select * from user_twitter_link` + where + ` order by created_at desc limit 1`
 
 	var out model.UserTwitterLink
-	err := DB.GetContext(ctx, &out, query, username, missionId, startTime)
+	err := DB.GetContext(ctx, &out, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -274,21 +347,14 @@ func GetUserTwitterLink(ctx context.Context, username string, missionId int64, s
 	return &out, nil
 }
 
-func GetKOLCommissionCredits(ctx context.Context, kolUserId string) (float64, error) {
-	var total float64
-
-	countQuery := `select ifnull(sum(m.credit),0) * 0.2 from users u left join user_mission m on u.username = m.username where from_kol_user_id = ?  group by u.username;`
-	countQueryIn, countQueryParams, err := sqlx.In(countQuery, kolUserId)
-	if err != nil {
-		return 0, err
-	}
-
-	err = DB.GetContext(ctx, &total, countQueryIn, countQueryParams...)
+// GetKOLCommissionCredits 获取 KOL 的分成积分
+func GetKOLCommissionCredits(ctx context.Context, kolUserId string) (int64, error) {
+	balance, err := GetCreditBalance(ctx, kolUserId)
 	if err != nil {
 		return 0, err
 	}
 
-	return total, nil
+	return balance.KolCredits, nil
 }
 
 func GetUserCreditsByKOLReferralCode(ctx context.Context, kolUserId string, option QueryOption) (int64, []*model.UserCredit, error) {
@@ -315,7 +381,7 @@ func GetUserCreditsByKOLReferralCode(ctx context.Context, kolUserId string, opti
 	}
 
 	query := `select * from (
-		select u.username, u.from_kol_ref_code , IFNULL(sum(m.credit),0) as credits, count(1) as completed_mission_count, u.created_at from users u left join user_mission m on u.username = m.username where from_kol_user_id = ?  group by u.username
+		select u.username, u.from_kol_ref_code , IFNULL(max(c.mission_credits),0) as credits, count(1) as completed_mission_count, u.created_at from users u left join user_mission m on u.username = m.username left join credit_balance c on u.username = c.username where from_kol_user_id = ?  group by u.username
 	) d order by created_at desc LIMIT ? OFFSET ?;`
 
 	var out []*model.UserCredit
@@ -327,64 +393,285 @@ func GetUserCreditsByKOLReferralCode(ctx context.Context, kolUserId string, opti
 	return total, out, nil
 }
 
-// AddUserMissionAndInviteLog 增加用户任务完成并且增加邀请的积分记录
+// AddUserMissionAndInviteLog 在同一事务中写入任务完成记录、邀请人及 KOL 的分成记录和对应的积分流水.
+// user_mission 的唯一索引保证同一用户同一任务(子任务)在同一周期(period)内只发放一次积分,
+// 重复写入时不做任何修改, um.ID 设置为已有完成记录的id并返回 ErrAlreadyAwarded
 func AddUserMissionAndInviteLog(ctx context.Context, um *model.UserMission) error {
-	query, args, err := squirrel.Insert(um.TableName()).Columns("username, mission_id, sub_mission_id, type, credit, content, created_at").
-		Values(um.Username, um.MissionID, um.SubMissionID, um.Type, um.Credit, um.Content, um.CreatedAt).ToSql()
+	tx, err := DB.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("generate insert user_mission sql error:%w", err)
+		return err
 	}
-	_, err = DB.ExecContext(ctx, query, args...)
+	defer tx.Rollback()
+
+	soldOut, err := addUserMission(ctx, tx, um)
 	if err != nil {
 		return err
 	}
 
-	// 查询该用户是否被邀请，有错误不返回
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// 任务刚好发放完时通知各实例刷新, 避免继续提交校验
+	if soldOut {
+		table, _ := capacityTarget(um)
+		notifyMissionChanged(ctx, table)
+	}
+
+	return nil
+}
+
+// addUserMission 在事务中发放任务积分, 返回本次发放后任务是否已发放完, 调用方提交事务后负责通知.
+// 重复写入时 um.ID 设置为已有完成记录的id并返回 ErrAlreadyAwarded, 事务中的其他修改由调用方回滚
+func addUserMission(ctx context.Context, tx *sqlx.Tx, um *model.UserMission) (bool, error) {
+	// 每日任务按连续完成天数计算奖励, 在名额及积分入账后单独发放
+	var (
+		us    *model.UserStreak
+		bonus int64
+		err   error
+	)
+	if um.Type == missionTypeDaily && um.SubMissionID == 0 {
+		us, bonus, err = applyDailyStreak(ctx, tx, um)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	query, args, err := squirrel.Insert(um.TableName()).Columns("username, mission_id, sub_mission_id, type, credit, content, period, created_at").
+		Values(um.Username, um.MissionID, um.SubMissionID, um.Type, um.Credit, um.Content, um.Period, um.CreatedAt).ToSql()
+	if err != nil {
+		return false, fmt.Errorf("generate insert user_mission sql error:%w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if isDuplicateEntry(err) {
+		return false, getAwardedUserMissionId(ctx, tx, um)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	um.ID, err = res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	mission, err := getAwardMission(ctx, tx, "mission", um.MissionID)
+	if err != nil {
+		return false, err
+	}
+
+	soldOut := false
+	if mission != nil {
+		soldOut, err = consumeMissionCapacity(ctx, tx, mission, um)
+		if err != nil {
+			return false, err
+		}
+
+		// 赛季任务的积分同时计入赛季积分
+		um.SeasonID, err = awardSeasonId(ctx, tx, mission, um)
+		if err != nil {
+			return false, err
+		}
+
+		if um.SeasonID > 0 {
+			if _, err := tx.ExecContext(ctx, `update user_mission set season_id = ? where id = ?`, um.SeasonID, um.ID); err != nil {
+				return false, err
+			}
+
+			if err := addSeasonCredits(ctx, tx, um.SeasonID, um.Username, um.Credit, 0); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	_, err = appendCreditEntry(ctx, tx, &model.CreditLedger{
+		Username:  um.Username,
+		Type:      model.CreditEntryMission,
+		RefID:     strconv.FormatInt(um.ID, 10),
+		Amount:    um.Credit,
+		Reason:    fmt.Sprintf("complete mission %d", um.MissionID),
+		CreatedAt: um.CreatedAt,
+	}, creditBucket(model.CreditEntryMission))
+	if err != nil {
+		return false, err
+	}
+
+	if us != nil {
+		if err := saveUserStreak(ctx, tx, us); err != nil {
+			return false, err
+		}
+
+		if bonus > 0 {
+			if err := addStreakBonus(ctx, tx, um, us, bonus); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// 查询该用户是否被邀请, 存在的话则增加邀请人的分成记录
 	userExt, err := GetUserExt(ctx, um.Username)
 	switch err {
 	case sql.ErrNoRows:
 	case nil:
-		// 存在的话则增加记录
 		if strings.TrimSpace(userExt.InvitedCode) != "" {
-			addInviteLog(ctx, userExt, um)
+			if err := addInviteLog(ctx, tx, userExt, um); err != nil {
+				return false, err
+			}
 		}
+	default:
+		return false, err
 	}
 
-	return nil
+	// 绑定了 KOL 邀请码的用户增加 KOL 分成
+	user, err := GetUserByUsername(ctx, um.Username)
+	switch err {
+	case sql.ErrNoRows:
+	case nil:
+		if user.FromKolUserID != "" {
+			if err := addKOLCommission(ctx, tx, user.FromKolUserID, um); err != nil {
+				return false, err
+			}
+		}
+	default:
+		return false, err
+	}
+
+	return soldOut, nil
 }
 
-func addInviteLog(ctx context.Context, ue *model.UsersExt, um *model.UserMission) error {
+// getAwardMission 获取发放积分的任务(子任务), 不锁定任务行, 任务不存在时返回 nil
+func getAwardMission(ctx context.Context, tx *sqlx.Tx, table string, id int64) (*model.Mission, error) {
+	var mission model.Mission
+	err := tx.GetContext(ctx, &mission, fmt.Sprintf(`select * from %s where id = ?`, table), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &mission, nil
+}
+
+// capacityTarget 完成记录计入上限的任务, 子任务的完成记录计入子任务自己的上限
+func capacityTarget(um *model.UserMission) (string, int64) {
+	if um.SubMissionID > 0 {
+		return "sub_mission", um.SubMissionID
+	}
+	return "mission", um.MissionID
+}
+
+// consumeMissionCapacity 扣减任务(子任务)的完成人次及积分预算, 已用完时返回 ErrMissionSoldOut, 否则返回本次发放后是否已发放完.
+// 只有设置了上限的任务才更新计数, 条件更新保证并发发放时不会超发, 未设置上限的任务不会争用任务行
+func consumeMissionCapacity(ctx context.Context, tx *sqlx.Tx, mission *model.Mission, um *model.UserMission) (bool, error) {
+	table, id := capacityTarget(um)
+
+	target := mission
+	if um.SubMissionID > 0 {
+		sub, err := getAwardMission(ctx, tx, table, id)
+		if err != nil || sub == nil {
+			return false, err
+		}
+		target = sub
+	}
+
+	if target.MaxCompletions <= 0 && target.CreditBudget <= 0 {
+		return false, nil
+	}
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`update %s set completions = completions + 1, credits_awarded = credits_awarded + ?
+		where id = ? and (max_completions <= 0 or completions < max_completions) and (credit_budget <= 0 or credits_awarded + ? <= credit_budget)`, table),
+		um.Credit, id, um.Credit)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if n == 0 {
+		return false, ErrMissionSoldOut
+	}
+
+	// 读取更新后的计数, 判断是否刚好发放完
+	if err := tx.GetContext(ctx, target, fmt.Sprintf(`select * from %s where id = ?`, table), id); err != nil {
+		return false, err
+	}
+
+	return target.SoldOut(target.Credit), nil
+}
+
+// releaseMissionCapacity 撤销完成记录时归还任务(子任务)的完成人次及积分预算, 未设置上限的任务不统计
+func releaseMissionCapacity(ctx context.Context, tx *sqlx.Tx, um *model.UserMission) error {
+	table, id := capacityTarget(um)
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`update %s set completions = if(completions > 0, completions - 1, 0),
+		credits_awarded = if(credits_awarded > ?, credits_awarded - ?, 0) where id = ? and (max_completions > 0 or credit_budget > 0)`, table),
+		um.Credit, um.Credit, id)
+	return err
+}
+
+func addInviteLog(ctx context.Context, tx *sqlx.Tx, ue *model.UsersExt, um *model.UserMission) error {
 	// 根据邀请人的邀请码获取邀请人信息
 	ui, err := GetUserExtByInviteCode(ctx, ue.InvitedCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
 	if err != nil {
 		return err
 	}
 
 	// 增加记录
-	credit := um.Credit * config.Cfg.InviteShareRate / 100
-	err = CreateInviteLog(ctx, &model.InviteLog{
-		Username:    ui.Username,
-		InvitedName: ue.Username,
-		MissionID:   um.MissionID,
-		Credit:      credit,
-		CreatedAt:   time.Now(),
-	})
+	inviteLog := &model.InviteLog{
+		Username:      ui.Username,
+		InvitedName:   ue.Username,
+		MissionID:     um.MissionID,
+		Credit:        um.Credit * config.Cfg.InviteShareRate / 100,
+		UserMissionID: um.ID,
+		CreatedAt:     time.Now(),
+	}
+
+	inviteLog.ID, err = createInviteLog(ctx, tx, inviteLog)
+	if err != nil {
+		return err
+	}
 
+	if um.SeasonID > 0 {
+		if err := addSeasonCredits(ctx, tx, um.SeasonID, inviteLog.Username, 0, inviteLog.Credit); err != nil {
+			return err
+		}
+	}
+
+	_, err = appendCreditEntry(ctx, tx, &model.CreditLedger{
+		Username:  inviteLog.Username,
+		Type:      model.CreditEntryInviteCommission,
+		RefID:     strconv.FormatInt(inviteLog.ID, 10),
+		Amount:    inviteLog.Credit,
+		Reason:    fmt.Sprintf("%s completed mission %d", ue.Username, um.MissionID),
+		CreatedAt: inviteLog.CreatedAt,
+	}, creditBucket(model.CreditEntryInviteCommission))
 	return err
 }
 
-// SumInviteCredits 统计邀请积分的总和
-func SumInviteCredits(ctx context.Context, username string) (int64, error) {
-	var sum int64
-	iv := model.InviteLog{}
-
-	query, args, err := squirrel.Select("ifnull(sum(credit),0)").From(iv.TableName()).Where("username = ?", username).ToSql()
-	if err != nil {
-		return 0, fmt.Errorf("generate select sum of credits error:%w", err)
+// getAwardedUserMissionId 查询同一周期已有的完成记录id, 查询成功时返回 ErrAlreadyAwarded.
+// 使用加锁读取, 事务中也能读到并发事务刚提交的完成记录
+func getAwardedUserMissionId(ctx context.Context, tx *sqlx.Tx, um *model.UserMission) error {
+	query := `select id from user_mission where username = ? and mission_id = ? and sub_mission_id = ? and period = ? lock in share mode`
+	if err := tx.GetContext(ctx, &um.ID, query, um.Username, um.MissionID, um.SubMissionID, um.Period); err != nil {
+		return err
 	}
+	return ErrAlreadyAwarded
+}
 
-	err = DB.QueryRowxContext(ctx, query, args...).Scan(&sum)
-	return sum, err
+// isDuplicateEntry 判断是否违反唯一索引
+func isDuplicateEntry(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == mysqlErrDupEntry
 }
 
 // GetMissionLogs 获取任务完成记录
@@ -406,8 +693,14 @@ func GetMissionLogs(ctx context.Context, name string, option QueryOption) ([]*mo
 		offset = limit * (option.Page - 1)
 	}
 
+	where := squirrel.Eq{"user_mission.username": name}
+	if option.SeasonID > 0 {
+		// 不属于任何赛季的任务在所有赛季中都展示, 与任务列表一致
+		where["user_mission.season_id"] = []int64{option.SeasonID, 0}
+	}
+
 	// 获取总条数
-	query, args, err := squirrel.Select("COUNT(id)").From(um.TableName()).Where("username = ?", name).ToSql()
+	query, args, err := squirrel.Select("COUNT(id)").From(um.TableName()).Where(where).ToSql()
 	if err != nil {
 		return nil, 0, fmt.Errorf("generate sql error:%w", err)
 	}
@@ -418,7 +711,7 @@ func GetMissionLogs(ctx context.Context, name string, option QueryOption) ([]*mo
 	// 获取详情
 	query, args, err = squirrel.Select("title,title_cn,user_mission.created_at AS createdAt,user_mission.credit AS ucredit").
 		From(um.TableName()).LeftJoin("mission ON user_mission.mission_id = mission.id").
-		Where("username = ?", name).Limit(uint64(limit)).Offset(uint64(offset)).OrderBy("createdAt DESC").ToSql()
+		Where(where).Limit(uint64(limit)).Offset(uint64(offset)).OrderBy("createdAt DESC").ToSql()
 	if err != nil {
 		return nil, 0, fmt.Errorf("generate sql error:%w", err)
 	}
@@ -440,7 +733,7 @@ func GetCreditsList(ctx context.Context, option QueryOption) (int64, []*model.Us
 		offset = limit * (option.Page - 1)
 	}
 
-	userCredits, err := GetUserCreditsFromCache(ctx)
+	userCredits, err := GetUserCreditsFromCache(ctx, option.SeasonID)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -458,22 +751,26 @@ func GetCreditsList(ctx context.Context, option QueryOption) (int64, []*model.Us
 	return int64(total), userCredits[offset : offset+limit], nil
 }
 
-func GetUserCreditsFromCache(ctx context.Context) ([]*model.UserCredit, error) {
-	userCredits, err := GetUserCredits(ctx)
+// GetUserCreditsFromCache 获取积分排行榜, seasonId 大于 0 时获取赛季积分排行榜
+func GetUserCreditsFromCache(ctx context.Context, seasonId int64) ([]*model.UserCredit, error) {
+	userCredits, err := GetUserCredits(ctx, seasonId)
 	if err == nil {
 		return userCredits, nil
 	}
 
-	query := `select * from (
-		select t.username, IFNULL(sum(t.credit),0) as credits from (select username, credit from user_mission union all select username, credit from invite_log) t group by t.username 
-	) d order by d.credits desc limit 500`
-
-	err = DB.SelectContext(ctx, &userCredits, query)
+	if seasonId > 0 {
+		query := `select c.username, c.credits from season_credit c join users u on c.username = u.username
+			where c.season_id = ? and c.credits > 0 order by c.credits desc limit 500`
+		err = DB.SelectContext(ctx, &userCredits, query, seasonId)
+	} else {
+		query := `select c.username, c.balance as credits from credit_balance c join users u on c.username = u.username order by c.balance desc limit 500`
+		err = DB.SelectContext(ctx, &userCredits, query)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	err = SaveUserCredits(ctx, userCredits)
+	err = SaveUserCredits(ctx, seasonId, userCredits)
 	if err != nil {
 		return nil, err
 	}
@@ -481,8 +778,15 @@ func GetUserCreditsFromCache(ctx context.Context) ([]*model.UserCredit, error) {
 	return userCredits, nil
 }
 
-func SaveUserCredits(ctx context.Context, uc []*model.UserCredit) error {
-	key := fmt.Sprintf("TITAN::QUEST::USER::CREDITS")
+func userCreditsKey(seasonId int64) string {
+	if seasonId > 0 {
+		return fmt.Sprintf("TITAN::QUEST::USER::CREDITS::SEASON::%d", seasonId)
+	}
+	return "TITAN::QUEST::USER::CREDITS"
+}
+
+func SaveUserCredits(ctx context.Context, seasonId int64, uc []*model.UserCredit) error {
+	key := userCreditsKey(seasonId)
 	data, err := json.Marshal(uc)
 	if err != nil {
 		return err
@@ -491,8 +795,8 @@ func SaveUserCredits(ctx context.Context, uc []*model.UserCredit) error {
 	return err
 }
 
-func GetUserCredits(ctx context.Context) ([]*model.UserCredit, error) {
-	key := fmt.Sprintf("TITAN::QUEST::USER::CREDITS")
+func GetUserCredits(ctx context.Context, seasonId int64) ([]*model.UserCredit, error) {
+	key := userCreditsKey(seasonId)
 	data, err := RedisCache.Get(ctx, key).Bytes()
 	if err != nil {
 		return nil, err
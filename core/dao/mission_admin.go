@@ -0,0 +1,137 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// 任务表及子任务表, 两张表结构相同
+const (
+	MissionTable    = "mission"
+	SubMissionTable = "sub_mission"
+)
+
+// ListAllMissions 获取表中所有状态的任务, parentId 大于 0 时只获取该任务的子任务
+func ListAllMissions(ctx context.Context, table string, parentId int64) ([]*model.Mission, error) {
+	query := fmt.Sprintf(`select * from %s`, table)
+	var args []interface{}
+	if parentId > 0 {
+		query += ` where parent_id = ?`
+		args = append(args, parentId)
+	}
+	query += ` order by sort_id, id`
+
+	var out []*model.Mission
+	err := DB.SelectContext(ctx, &out, query, args...)
+	return out, err
+}
+
+// GetMissionFrom 获取表中任意状态的任务
+func GetMissionFrom(ctx context.Context, table string, id int64) (*model.Mission, error) {
+	var out model.Mission
+	err := DB.GetContext(ctx, &out, fmt.Sprintf(`select * from %s where id = ?`, table), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// CreateMissionIn 在表中创建任务
+func CreateMissionIn(ctx context.Context, table string, m *model.Mission) error {
+	query := fmt.Sprintf(`insert into %s(title, title_cn, channel, logo, credit, status, open_url, target_id, verifier, params,
+		start_time, end_time, type, recurrence, sort_id, parent_id, max_completions, credit_budget, season_id, created_at, updated_at)
+		values(:title, :title_cn, :channel, :logo, :credit, :status, :open_url, :target_id, :verifier, :params,
+		:start_time, :end_time, :type, :recurrence, :sort_id, :parent_id, :max_completions, :credit_budget, :season_id, now(), now())`, table)
+
+	res, err := DB.NamedExecContext(ctx, query, m)
+	if err != nil {
+		return err
+	}
+
+	m.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	notifyMissionChanged(ctx, table)
+	return nil
+}
+
+// UpdateMissionIn 修改任务配置, 状态及已发放的统计不在这里修改
+func UpdateMissionIn(ctx context.Context, table string, m *model.Mission) error {
+	query := fmt.Sprintf(`update %s set title = :title, title_cn = :title_cn, channel = :channel, logo = :logo, credit = :credit,
+		open_url = :open_url, target_id = :target_id, verifier = :verifier, params = :params, start_time = :start_time,
+		end_time = :end_time, type = :type, recurrence = :recurrence, sort_id = :sort_id, parent_id = :parent_id,
+		max_completions = :max_completions, credit_budget = :credit_budget, season_id = :season_id, updated_at = now()
+		where id = :id`, table)
+
+	_, err := DB.NamedExecContext(ctx, query, m)
+	if err != nil {
+		return err
+	}
+
+	notifyMissionChanged(ctx, table)
+	return nil
+}
+
+// UpdateMissionSortIds 在同一事务中修改多个任务的排序
+func UpdateMissionSortIds(ctx context.Context, table string, sortIds map[int64]int32) error {
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`update %s set sort_id = ?, updated_at = now() where id = ?`, table)
+	for id, sortId := range sortIds {
+		res, err := tx.ExecContext(ctx, query, sortId, id)
+		if err != nil {
+			return err
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			// 排序未变化时也会返回 0, 需要确认任务存在
+			var exists bool
+			err := tx.GetContext(ctx, &exists, fmt.Sprintf(`select count(*) > 0 from %s where id = ?`, table), id)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				return fmt.Errorf("mission %d: %w", id, ErrNoRow)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	notifyMissionChanged(ctx, table)
+	return nil
+}
+
+// UpdateMissionStatusIn 将任务状态从 from 修改为 to, 状态已被修改时返回 false
+func UpdateMissionStatusIn(ctx context.Context, table string, id int64, from, to int32) (bool, error) {
+	query := fmt.Sprintf(`update %s set status = ?, updated_at = now() where id = ? and status = ?`, table)
+	result, err := DB.ExecContext(ctx, query, to, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected > 0 {
+		notifyMissionChanged(ctx, table)
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,31 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// missionChangedChannel 任务配置修改的通知频道, 消息为修改的表名
+const missionChangedChannel = "TITAN::QUEST::MISSION::CHANGED"
+
+// PublishMissionChanged 通知所有实例任务或子任务已修改
+func PublishMissionChanged(ctx context.Context, table string) error {
+	return RedisCache.Publish(ctx, missionChangedChannel, table).Err()
+}
+
+// SubscribeMissionChanged 订阅任务修改通知
+func SubscribeMissionChanged(ctx context.Context) *redis.PubSub {
+	return RedisCache.Subscribe(ctx, missionChangedChannel)
+}
+
+// notifyMissionChanged 修改已提交后发布通知, 发布失败时由各实例的定期刷新兜底
+func notifyMissionChanged(ctx context.Context, table string) {
+	if RedisCache == nil {
+		return
+	}
+
+	if err := PublishMissionChanged(ctx, table); err != nil {
+		log.Errorf("publish mission changed: %v", err)
+	}
+}
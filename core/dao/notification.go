@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// AddUserNotification 添加一条用户站内通知
+func AddUserNotification(ctx context.Context, n *model.UserNotification) error {
+	query := `insert into user_notification(username, type, params, is_read, created_at) values(:username, :type, :params, 0, now())`
+
+	_, err := DB.NamedExecContext(ctx, query, n)
+	return err
+}
+
+// GetUserNotifications 获取用户的站内通知及未读数量
+func GetUserNotifications(ctx context.Context, username string, option QueryOption) ([]*model.UserNotification, int64, int64, error) {
+	var (
+		limit, offset int
+		total, unread int64
+		out           []*model.UserNotification
+	)
+
+	if option.PageSize <= 0 {
+		limit = 50
+	} else {
+		limit = option.PageSize
+	}
+	if option.Page > 0 {
+		offset = limit * (option.Page - 1)
+	}
+
+	err := DB.GetContext(ctx, &total, `select count(id) from user_notification where username = ?`, username)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("get total of user_notification error:%w", err)
+	}
+
+	err = DB.GetContext(ctx, &unread, `select count(id) from user_notification where username = ? and is_read = 0`, username)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("get unread of user_notification error:%w", err)
+	}
+
+	query, args, err := squirrel.Select("*").From("user_notification").Where("username = ?", username).
+		OrderBy("id DESC").Limit(uint64(limit)).Offset(uint64(offset)).ToSql()
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("generate sql error:%w", err)
+	}
+	err = DB.SelectContext(ctx, &out, query, args...)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("get list of user_notification error:%w", err)
+	}
+
+	return out, total, unread, nil
+}
+
+// MarkUserNotificationsRead 将用户的通知标记为已读, ids 为空时标记全部
+func MarkUserNotificationsRead(ctx context.Context, username string, ids []int64) error {
+	builder := squirrel.Update("user_notification").Set("is_read", 1).Where("username = ?", username)
+	if len(ids) > 0 {
+		builder = builder.Where(squirrel.Eq{"id": ids})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("generate sql error:%w", err)
+	}
+
+	_, err = DB.ExecContext(ctx, query, args...)
+	return err
+}
@@ -32,13 +32,13 @@ func ListOperationLog(ctx context.Context, option QueryOption) ([]*model.Operati
 
 	err := DB.GetContext(ctx, &total, fmt.Sprintf(
 		`SELECT count(*) FROM operation_log`,
-	), args)
+	), args...)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	err = DB.SelectContext(ctx, &out, fmt.Sprintf(
-		`SELECT * FROM operation_log LIMIT %d OFFSET %d`, limit, offset,
+		`SELECT * FROM operation_log ORDER BY id DESC LIMIT %d OFFSET %d`, limit, offset,
 	), args...)
 	if err != nil {
 		return nil, 0, err
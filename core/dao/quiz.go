@@ -0,0 +1,106 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+var (
+	// ErrQuizAttemptsExceeded 当前周期的答题次数已用完
+	ErrQuizAttemptsExceeded = errors.New("no quiz attempts left")
+	// ErrQuizPassed 当前周期已通过答题
+	ErrQuizPassed = errors.New("quiz has been passed")
+)
+
+// GetQuizQuestions 按排序获取答题任务的题目
+func GetQuizQuestions(ctx context.Context, missionId int64) ([]*model.QuizQuestion, error) {
+	var out []*model.QuizQuestion
+	err := DB.SelectContext(ctx, &out, `select * from quiz_question where mission_id = ? order by sort_id, id`, missionId)
+	return out, err
+}
+
+// SaveQuizQuestion 新增或修改题目, id 为 0 时新增
+func SaveQuizQuestion(ctx context.Context, q *model.QuizQuestion) error {
+	if q.ID > 0 {
+		res, err := DB.NamedExecContext(ctx, `update quiz_question set mission_id = :mission_id, question = :question, question_cn = :question_cn,
+			options = :options, answer = :answer, sort_id = :sort_id, updated_at = now() where id = :id`, q)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return fmt.Errorf("quiz question %d: %w", q.ID, ErrNoRow)
+		}
+		return nil
+	}
+
+	res, err := DB.NamedExecContext(ctx, `insert into quiz_question(mission_id, question, question_cn, options, answer, sort_id, created_at, updated_at)
+		values(:mission_id, :question, :question_cn, :options, :answer, :sort_id, now(), now())`, q)
+	if err != nil {
+		return err
+	}
+
+	q.ID, err = res.LastInsertId()
+	return err
+}
+
+func DeleteQuizQuestion(ctx context.Context, id int64) error {
+	_, err := DB.ExecContext(ctx, `delete from quiz_question where id = ?`, id)
+	return err
+}
+
+// AddQuizAttempt 添加一条答题记录, maxAttempts 大于 0 时限制每个周期的答题次数, 通过后不能再答题
+func AddQuizAttempt(ctx context.Context, a *model.QuizAttempt, maxAttempts int) error {
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stat struct {
+		Attempts int  `db:"attempts"`
+		Passed   bool `db:"passed"`
+	}
+	err = tx.GetContext(ctx, &stat, `select count(*) as attempts, coalesce(max(passed), 0) as passed from quiz_attempt
+		where username = ? and mission_id = ? and period = ? for update`, a.Username, a.MissionID, a.Period)
+	if err != nil {
+		return err
+	}
+
+	if stat.Passed {
+		return ErrQuizPassed
+	}
+
+	if maxAttempts > 0 && stat.Attempts >= maxAttempts {
+		return ErrQuizAttemptsExceeded
+	}
+
+	res, err := tx.NamedExecContext(ctx, `insert into quiz_attempt(username, mission_id, period, score, passed, answers, created_at)
+		values(:username, :mission_id, :period, :score, :passed, :answers, now())`, a)
+	if err != nil {
+		return err
+	}
+
+	a.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetQuizAttempts 获取用户在任务周期内的答题记录
+func GetQuizAttempts(ctx context.Context, username string, missionId int64, period string) ([]*model.QuizAttempt, error) {
+	var out []*model.QuizAttempt
+	err := DB.SelectContext(ctx, &out, `select * from quiz_attempt where username = ? and mission_id = ? and period = ? order by id`,
+		username, missionId, period)
+	return out, err
+}
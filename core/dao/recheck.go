@@ -0,0 +1,164 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// 完成记录的重新校验状态
+const (
+	RecheckStatusPassed  = "passed"
+	RecheckStatusFailing = "failing"
+	RecheckStatusRevoked = "revoked"
+	RecheckStatusFlagged = "flagged"
+)
+
+// GetUserMissionsForRecheck 按最久未校验优先, 获取指定类型及状态且未结束的任务中需要重新校验的完成记录.
+// 包括从未校验或上次校验通过且早于 checkedBefore 的记录, 以及宽限期已过 (失败时间早于 failedBefore) 的失败记录
+func GetUserMissionsForRecheck(ctx context.Context, missionType, missionStatus int32, verifiers []string, checkedBefore, failedBefore time.Time, limit int) ([]*model.UserMission, error) {
+	if len(verifiers) == 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In(`select um.* from user_mission um
+		join mission m on m.id = um.mission_id
+		left join user_mission_recheck r on r.user_mission_id = um.id
+		where m.type = ? and m.status = ? and (m.end_time <= m.start_time or m.end_time > now())
+		and m.verifier in (?) and um.sub_mission_id = 0 and (
+			(r.user_mission_id is null and um.created_at < ?) or
+			(r.status = ? and r.checked_at < ?) or
+			(r.status = ? and r.failed_at < ?))
+		order by ifnull(r.checked_at, um.created_at) asc limit ?`,
+		missionType, missionStatus, verifiers, checkedBefore,
+		RecheckStatusPassed, checkedBefore,
+		RecheckStatusFailing, failedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*model.UserMission
+	err = DB.SelectContext(ctx, &out, DB.Rebind(query), args...)
+	return out, err
+}
+
+// GetUserMissionRecheck 获取完成记录的重新校验状态, 从未校验时返回 sql.ErrNoRows
+func GetUserMissionRecheck(ctx context.Context, userMissionId int64) (*model.UserMissionRecheck, error) {
+	var out model.UserMissionRecheck
+	err := DB.GetContext(ctx, &out, `select * from user_mission_recheck where user_mission_id = ?`, userMissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// SaveUserMissionRecheck 保存完成记录的重新校验状态
+func SaveUserMissionRecheck(ctx context.Context, r *model.UserMissionRecheck) error {
+	query := `insert into user_mission_recheck(user_mission_id, username, mission_id, status, reason, checked_at, failed_at, created_at, updated_at)
+		values(:user_mission_id, :username, :mission_id, :status, :reason, :checked_at, :failed_at, now(), now())
+		on duplicate key update status = values(status), reason = values(reason), checked_at = values(checked_at),
+		failed_at = values(failed_at), updated_at = now()`
+
+	_, err := DB.NamedExecContext(ctx, query, r)
+	return err
+}
+
+// RevokeUserMission 撤销一条任务完成记录: 撤销该记录产生的任务积分、邀请分成及 KOL 分成并删除完成记录, 用户之后可以重新完成任务
+func RevokeUserMission(ctx context.Context, um *model.UserMission, reason, operator string) error {
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	refId := strconv.FormatInt(um.ID, 10)
+
+	var entries []*model.CreditLedger
+	err = tx.SelectContext(ctx, &entries, `select * from credit_ledger where
+		(type in (?, ?, ?) and ref_id = ?) or
+		(type = ? and ref_id in (select cast(id as char) from invite_log where user_mission_id = ?))`,
+		model.CreditEntryMission, model.CreditEntryKOLCommission, model.CreditEntryStreakBonus, refId,
+		model.CreditEntryInviteCommission, um.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err := revokeCreditEntry(ctx, tx, entry, reason, operator)
+		if err != nil && !errors.Is(err, ErrCreditEntryRevoked) {
+			return err
+		}
+	}
+
+	if um.SeasonID > 0 {
+		if err := revokeSeasonCredits(ctx, tx, um); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `delete from invite_log where user_mission_id = ?`, um.ID); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `delete from user_mission where id = ?`, um.ID)
+	if err != nil {
+		return err
+	}
+
+	released := false
+	if n, _ := res.RowsAffected(); n > 0 {
+		if err := releaseMissionCapacity(ctx, tx, um); err != nil {
+			return err
+		}
+		released = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if released {
+		notifyMissionChanged(ctx, MissionTable)
+	}
+
+	return nil
+}
+
+// revokeSeasonCredits 扣减完成记录计入的赛季积分, 包括邀请人及 KOL 的分成
+func revokeSeasonCredits(ctx context.Context, tx *sqlx.Tx, um *model.UserMission) error {
+	if err := addSeasonCredits(ctx, tx, um.SeasonID, um.Username, -um.Credit, 0); err != nil {
+		return err
+	}
+
+	var kolEntries []*model.CreditLedger
+	err := tx.SelectContext(ctx, &kolEntries, `select * from credit_ledger where type = ? and ref_id = ?`,
+		model.CreditEntryKOLCommission, strconv.FormatInt(um.ID, 10))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range kolEntries {
+		if err := addSeasonCredits(ctx, tx, um.SeasonID, entry.Username, 0, -entry.Amount); err != nil {
+			return err
+		}
+	}
+
+	var inviteLogs []*model.InviteLog
+	err = tx.SelectContext(ctx, &inviteLogs, `select * from invite_log where user_mission_id = ?`, um.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, il := range inviteLogs {
+		if err := addSeasonCredits(ctx, tx, um.SeasonID, il.Username, 0, -il.Credit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
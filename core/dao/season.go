@@ -0,0 +1,106 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetSeasons 获取全部赛季, 包括已归档的赛季
+func GetSeasons(ctx context.Context) ([]*model.Season, error) {
+	var out []*model.Season
+	err := DB.SelectContext(ctx, &out, `select * from season order by start_time desc`)
+	return out, err
+}
+
+func GetSeasonById(ctx context.Context, id int64) (*model.Season, error) {
+	var out model.Season
+	err := DB.GetContext(ctx, &out, `select * from season where id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// GetCurrentSeason 获取 now 时刻进行中的赛季, 有多个时取最晚开始的一个, 没有时返回 sql.ErrNoRows.
+// 结束时间不晚于开始时间时视为不限结束时间
+func GetCurrentSeason(ctx context.Context, now time.Time) (*model.Season, error) {
+	var out model.Season
+	err := DB.GetContext(ctx, &out, `select * from season where status = ? and start_time <= ? and (end_time <= start_time or end_time > ?)
+		order by start_time desc limit 1`, model.SeasonStatusActive, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// addSeasonCredits 增加用户的赛季积分, 扣减时传入负数
+func addSeasonCredits(ctx context.Context, tx *sqlx.Tx, seasonId int64, username string, missionCredits, inviteCredits int64) error {
+	_, err := tx.ExecContext(ctx, `insert into season_credit(season_id, username, credits, mission_credits, invite_credits, updated_at) values(?, ?, ?, ?, ?, now())
+		on duplicate key update credits = credits + values(credits), mission_credits = mission_credits + values(mission_credits),
+		invite_credits = invite_credits + values(invite_credits), updated_at = now()`,
+		seasonId, username, missionCredits+inviteCredits, missionCredits, inviteCredits)
+	return err
+}
+
+// awardSeasonId 完成记录计入的赛季, 子任务设置了赛季时使用子任务的赛季, 否则使用父任务的赛季
+func awardSeasonId(ctx context.Context, tx *sqlx.Tx, mission *model.Mission, um *model.UserMission) (int64, error) {
+	if um.SubMissionID > 0 {
+		var seasonId int64
+		err := tx.GetContext(ctx, &seasonId, `select season_id from sub_mission where id = ?`, um.SubMissionID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+
+		if seasonId > 0 {
+			return seasonId, nil
+		}
+	}
+
+	return mission.SeasonID, nil
+}
+
+// GetSeasonCredit 获取用户的赛季积分, 没有积分时返回零值
+func GetSeasonCredit(ctx context.Context, seasonId int64, username string) (*model.SeasonCredit, error) {
+	var out []*model.SeasonCredit
+	err := DB.SelectContext(ctx, &out, `select * from season_credit where season_id = ? and username = ?`, seasonId, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return &model.SeasonCredit{SeasonID: seasonId, Username: username}, nil
+	}
+
+	return out[0], nil
+}
+
+// SeasonTotal 赛季的参与人数及发放的积分总数
+type SeasonTotal struct {
+	SeasonID     int64 `db:"season_id"`
+	Participants int64 `db:"participants"`
+	Credits      int64 `db:"credits"`
+}
+
+// GetSeasonTotals 获取各赛季的参与人数及发放的积分总数, 按赛季id索引, 没有积分记录的赛季不返回
+func GetSeasonTotals(ctx context.Context) (map[int64]*SeasonTotal, error) {
+	var totals []*SeasonTotal
+	err := DB.SelectContext(ctx, &totals, `select season_id, count(*) as participants, ifnull(sum(credits), 0) as credits
+		from season_credit group by season_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int64]*SeasonTotal, len(totals))
+	for _, total := range totals {
+		out[total.SeasonID] = total
+	}
+
+	return out, nil
+}
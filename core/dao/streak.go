@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gnasnik/titan-quest/config"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/gnasnik/titan-quest/pkg/streak"
+	"github.com/jmoiron/sqlx"
+)
+
+// missionTypeDaily 每日任务, 与 api.MissionTypeDaily 一致
+const missionTypeDaily = 2
+
+// StreakLocation 计算连续天数及任务周期使用的时区, 未配置或配置错误时使用服务器时区
+func StreakLocation() *time.Location {
+	if config.Cfg.Streak.Timezone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(config.Cfg.Streak.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// StreakTiers 连续完成的奖励档位
+func StreakTiers() []streak.Tier {
+	tiers := make([]streak.Tier, 0, len(config.Cfg.Streak.Bonus))
+	for _, b := range config.Cfg.Streak.Bonus {
+		tiers = append(tiers, streak.Tier{Days: b.Days, Percent: b.Percent})
+	}
+	return tiers
+}
+
+// applyDailyStreak 锁定用户每日任务的连续完成记录, 计算完成后的连续天数及连续奖励.
+// 需要在写入完成记录前调用, 返回的记录在完成记录写入成功后通过 saveUserStreak 保存
+func applyDailyStreak(ctx context.Context, tx *sqlx.Tx, um *model.UserMission) (*model.UserStreak, int64, error) {
+	_, err := tx.ExecContext(ctx, `insert into user_streak(username, mission_id, updated_at) values(?, ?, now())
+		on duplicate key update username = username`, um.Username, um.MissionID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var us model.UserStreak
+	err = tx.GetContext(ctx, &us, `select * from user_streak where username = ? and mission_id = ? for update`, um.Username, um.MissionID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	state := streak.State{
+		Current: us.CurrentStreak,
+		Longest: us.LongestStreak,
+		LastDay: us.LastDay,
+	}.Advance(streak.Day(um.CreatedAt, StreakLocation()))
+
+	us.CurrentStreak = state.Current
+	us.LongestStreak = state.Longest
+	us.LastDay = state.LastDay
+
+	return &us, streak.Bonus(StreakTiers(), state.Current, um.Credit), nil
+}
+
+func saveUserStreak(ctx context.Context, tx *sqlx.Tx, us *model.UserStreak) error {
+	_, err := tx.NamedExecContext(ctx, `update user_streak set current_streak = :current_streak, longest_streak = :longest_streak,
+		last_day = :last_day, updated_at = now() where username = :username and mission_id = :mission_id`, us)
+	return err
+}
+
+// addStreakBonus 连续奖励单独记一条流水, 不计入任务名额、预算、赛季积分及分成
+func addStreakBonus(ctx context.Context, tx *sqlx.Tx, um *model.UserMission, us *model.UserStreak, bonus int64) error {
+	_, err := appendCreditEntry(ctx, tx, &model.CreditLedger{
+		Username:  um.Username,
+		Type:      model.CreditEntryStreakBonus,
+		RefID:     strconv.FormatInt(um.ID, 10),
+		Amount:    bonus,
+		Reason:    fmt.Sprintf("mission %d %d day streak bonus", um.MissionID, us.CurrentStreak),
+		CreatedAt: um.CreatedAt,
+	}, creditBucket(model.CreditEntryStreakBonus))
+	return err
+}
+
+// GetUserStreaks 获取用户每日任务的连续完成记录
+func GetUserStreaks(ctx context.Context, username string) ([]*model.UserStreak, error) {
+	var out []*model.UserStreak
+	err := DB.SelectContext(ctx, &out, `select * from user_streak where username = ? and last_day <> ''`, username)
+	return out, err
+}
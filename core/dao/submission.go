@@ -0,0 +1,204 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// 人工审核任务的提交状态
+const (
+	SubmissionStatusPending int32 = iota
+	SubmissionStatusApproved
+	SubmissionStatusRejected
+)
+
+// ErrSubmissionExists 同一周期内已有待审核或已通过的提交
+var ErrSubmissionExists = errors.New("submission is under review or has been approved")
+
+// SubmissionFilter 审核队列的过滤条件, Status 小于 0 时不按状态过滤
+type SubmissionFilter struct {
+	Status    int32
+	MissionID int64
+	Username  string
+}
+
+// AddMissionSubmission 添加一条提交记录, 同一周期内被拒绝后可以重新提交
+func AddMissionSubmission(ctx context.Context, s *model.MissionSubmission) error {
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.GetContext(ctx, &exists, `select count(*) > 0 from mission_submission where username = ? and mission_id = ? and period = ? and status in (?, ?) for update`,
+		s.Username, s.MissionID, s.Period, SubmissionStatusPending, SubmissionStatusApproved)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrSubmissionExists
+	}
+
+	res, err := tx.NamedExecContext(ctx, `insert into mission_submission(username, mission_id, period, urls, content, status, created_at, updated_at)
+		values(:username, :mission_id, :period, :urls, :content, :status, now(), now())`, s)
+	if err != nil {
+		return err
+	}
+
+	s.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func GetMissionSubmission(ctx context.Context, id int64) (*model.MissionSubmission, error) {
+	var out model.MissionSubmission
+	err := DB.GetContext(ctx, &out, `select * from mission_submission where id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// GetMissionSubmissions 获取审核队列, 按提交时间先后排序
+func GetMissionSubmissions(ctx context.Context, filter SubmissionFilter, option QueryOption) ([]*model.MissionSubmission, int64, error) {
+	var (
+		limit, offset int
+		total         int64
+		out           []*model.MissionSubmission
+	)
+
+	if option.PageSize <= 0 {
+		limit = 50
+	} else {
+		limit = option.PageSize
+	}
+	if option.Page > 0 {
+		offset = limit * (option.Page - 1)
+	}
+
+	where := squirrel.And{}
+	if filter.Status >= 0 {
+		where = append(where, squirrel.Eq{"status": filter.Status})
+	}
+	if filter.MissionID > 0 {
+		where = append(where, squirrel.Eq{"mission_id": filter.MissionID})
+	}
+	if filter.Username != "" {
+		where = append(where, squirrel.Eq{"username": filter.Username})
+	}
+
+	query, args, err := squirrel.Select("COUNT(id)").From("mission_submission").Where(where).ToSql()
+	if err != nil {
+		return nil, 0, fmt.Errorf("generate sql error:%w", err)
+	}
+	err = DB.GetContext(ctx, &total, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get total of mission_submission error:%w", err)
+	}
+
+	query, args, err = squirrel.Select("*").From("mission_submission").Where(where).
+		OrderBy("id ASC").Limit(uint64(limit)).Offset(uint64(offset)).ToSql()
+	if err != nil {
+		return nil, 0, fmt.Errorf("generate sql error:%w", err)
+	}
+	err = DB.SelectContext(ctx, &out, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get list of mission_submission error:%w", err)
+	}
+
+	return out, total, nil
+}
+
+// GetLatestMissionSubmission 获取用户在任务周期内最近的一次提交
+func GetLatestMissionSubmission(ctx context.Context, username string, missionId int64, period string) (*model.MissionSubmission, error) {
+	var out model.MissionSubmission
+	err := DB.GetContext(ctx, &out, `select * from mission_submission where username = ? and mission_id = ? and period = ? order by id desc limit 1`,
+		username, missionId, period)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// GetUserLatestSubmissions 获取用户每个任务每个周期最近的一次提交
+func GetUserLatestSubmissions(ctx context.Context, username string) ([]*model.MissionSubmission, error) {
+	var out []*model.MissionSubmission
+	err := DB.SelectContext(ctx, &out, `select * from mission_submission where id in (
+		select max(id) from mission_submission where username = ? group by mission_id, period)`, username)
+	return out, err
+}
+
+// ReviewMissionSubmission 审核待审核的提交, 已被审核时返回 ErrNoRow
+func ReviewMissionSubmission(ctx context.Context, s *model.MissionSubmission) error {
+	res, err := DB.NamedExecContext(ctx, `update mission_submission set status = :status, reason = :reason, reviewer = :reviewer,
+		user_mission_id = :user_mission_id, reviewed_at = now(), updated_at = now() where id = :id and status = 0`, s)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("submission %d: %w", s.ID, ErrNoRow)
+	}
+
+	return nil
+}
+
+// ApproveMissionSubmission 审核通过待审核的提交并发放积分, 锁定提交记录, 发放积分与修改审核状态在同一事务中完成.
+// 已被审核时返回 ErrNoRow, 发放失败时提交仍为待审核; 同一周期已发放时关联已有的完成记录, 审核通过后返回 ErrAlreadyAwarded
+func ApproveMissionSubmission(ctx context.Context, s *model.MissionSubmission, um *model.UserMission) error {
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status int32
+	err = tx.GetContext(ctx, &status, `select status from mission_submission where id = ? for update`, s.ID)
+	if err != nil {
+		return err
+	}
+
+	if status != SubmissionStatusPending {
+		return fmt.Errorf("submission %d: %w", s.ID, ErrNoRow)
+	}
+
+	soldOut, awardErr := addUserMission(ctx, tx, um)
+	if awardErr != nil && !errors.Is(awardErr, ErrAlreadyAwarded) {
+		return awardErr
+	}
+
+	s.Status = SubmissionStatusApproved
+	s.UserMissionID = um.ID
+	_, err = tx.NamedExecContext(ctx, `update mission_submission set status = :status, reason = :reason, reviewer = :reviewer,
+		user_mission_id = :user_mission_id, reviewed_at = now(), updated_at = now() where id = :id`, s)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if soldOut {
+		table, _ := capacityTarget(um)
+		notifyMissionChanged(ctx, table)
+	}
+
+	return awardErr
+}
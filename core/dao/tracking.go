@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+const trackingOwnerKey = "TITAN::QUEST::TRACKING::OWNER::%s"
+
+// AddTrackingVisit 记录追踪链接的访问, 同一周期内 ip 或指纹重复的访问不记录, 返回是否为新的独立访问
+func AddTrackingVisit(ctx context.Context, v *model.TrackingVisit) (bool, error) {
+	res, err := DB.NamedExecContext(ctx, `insert ignore into tracking_visit(username, mission_id, period, ip, fingerprint, user_agent, created_at)
+		values(:username, :mission_id, :period, :ip, :fingerprint, :user_agent, now())`, v)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// CountTrackingVisits 统计用户追踪链接在任务周期内的独立访问次数
+func CountTrackingVisits(ctx context.Context, username string, missionId int64, period string) (int64, error) {
+	var count int64
+	err := DB.GetContext(ctx, &count, `select count(*) from tracking_visit where username = ? and mission_id = ? and period = ?`,
+		username, missionId, period)
+	return count, err
+}
+
+// AddTrackingOwnerIP 记录追踪链接所属用户使用的 ip, ttl 内该 ip 的访问不计入独立访问
+func AddTrackingOwnerIP(ctx context.Context, username, ip string, ttl time.Duration) error {
+	key := fmt.Sprintf(trackingOwnerKey, username)
+
+	pipe := RedisCache.TxPipeline()
+	pipe.SAdd(ctx, key, ip)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IsTrackingOwnerIP 判断访问的 ip 是否为追踪链接所属用户使用过的 ip
+func IsTrackingOwnerIP(ctx context.Context, username, ip string) (bool, error) {
+	return RedisCache.SIsMember(ctx, fmt.Sprintf(trackingOwnerKey, username), ip).Result()
+}
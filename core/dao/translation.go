@@ -0,0 +1,47 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+)
+
+// TranslationTable 文案表, 修改后通过任务修改通知各实例重新加载文案
+const TranslationTable = "translation"
+
+// GetTranslations 获取多语言文案, locale 为空时返回全部语言
+func GetTranslations(ctx context.Context, locale string) ([]*model.Translation, error) {
+	var out []*model.Translation
+	if locale == "" {
+		err := DB.SelectContext(ctx, &out, `select * from translation order by locale, msg_key`)
+		return out, err
+	}
+
+	err := DB.SelectContext(ctx, &out, `select * from translation where locale = ? order by msg_key`, locale)
+	return out, err
+}
+
+// SaveTranslation 新增或更新一条文案
+func SaveTranslation(ctx context.Context, t *model.Translation) error {
+	query := `insert into translation(locale, msg_key, text, created_at, updated_at) values(:locale, :msg_key, :text, now(), now())
+		on duplicate key update text = values(text), updated_at = now()`
+
+	_, err := DB.NamedExecContext(ctx, query, t)
+	if err != nil {
+		return err
+	}
+
+	notifyMissionChanged(ctx, TranslationTable)
+	return nil
+}
+
+// DeleteTranslation 删除一条文案, 删除后使用内置文案
+func DeleteTranslation(ctx context.Context, locale, key string) error {
+	_, err := DB.ExecContext(ctx, `delete from translation where locale = ? and msg_key = ?`, locale, key)
+	if err != nil {
+		return err
+	}
+
+	notifyMissionChanged(ctx, TranslationTable)
+	return nil
+}
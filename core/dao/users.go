@@ -8,6 +8,7 @@ import (
 
 	"github.com/Masterminds/squirrel"
 	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/jmoiron/sqlx"
 )
 
 func CreateUser(ctx context.Context, user *model.User) error {
@@ -65,9 +66,32 @@ func GetUserIds(ctx context.Context) ([]string, error) {
 	return out, nil
 }
 
+// UpdateUserKOLReferralCode 绑定 KOL 邀请码, 并为用户已完成的任务补发 KOL 分成
 func UpdateUserKOLReferralCode(ctx context.Context, username, kolReferralCode, kolUserId string) error {
-	_, err := DB.DB.ExecContext(ctx, `UPDATE users SET from_kol_ref_code = ?, from_kol_user_id = ?, updated_at = now() WHERE username = ?`, kolReferralCode, kolUserId, username)
-	return err
+	tx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET from_kol_ref_code = ?, from_kol_user_id = ?, updated_at = now() WHERE username = ?`, kolReferralCode, kolUserId, username)
+	if err != nil {
+		return err
+	}
+
+	var ums []*model.UserMission
+	err = tx.SelectContext(ctx, &ums, `select * from user_mission where username = ?`, username)
+	if err != nil {
+		return err
+	}
+
+	for _, um := range ums {
+		if err := addKOLCommission(ctx, tx, kolUserId, um); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // CreateUserInfo 创建用户信息
@@ -115,16 +139,20 @@ func CreateUserExt(ctx context.Context, userExt *model.UsersExt) error {
 	return err
 }
 
-// CreateInviteLog 增加邀请收益记录
-func CreateInviteLog(ctx context.Context, inviteLog *model.InviteLog) error {
-	query, args, err := squirrel.Insert(inviteLog.TableName()).Columns("username", "invited_name", "mission_id", "credit", "created_at").
-		Values(inviteLog.Username, inviteLog.InvitedName, inviteLog.MissionID, inviteLog.Credit, inviteLog.CreatedAt).ToSql()
+// createInviteLog 增加邀请收益记录, 返回记录id
+func createInviteLog(ctx context.Context, db sqlx.ExecerContext, inviteLog *model.InviteLog) (int64, error) {
+	query, args, err := squirrel.Insert(inviteLog.TableName()).Columns("username", "invited_name", "mission_id", "credit", "user_mission_id", "created_at").
+		Values(inviteLog.Username, inviteLog.InvitedName, inviteLog.MissionID, inviteLog.Credit, inviteLog.UserMissionID, inviteLog.CreatedAt).ToSql()
 	if err != nil {
-		return fmt.Errorf("generate insert invite_log sql error:%w", err)
+		return 0, fmt.Errorf("generate insert invite_log sql error:%w", err)
 	}
 
-	_, err = DB.DB.ExecContext(ctx, query, args...)
-	return err
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
 }
 
 // GetUserExt 获取用户附属信息
@@ -165,8 +193,9 @@ func GetUserExtByInviteCode(ctx context.Context, inviteCode string) (*model.User
 func GetUserResponse(ctx context.Context, username string) (*model.ResponseUser, error) {
 	response := model.ResponseUser{}
 
-	query, args, err := squirrel.Select("users.username AS un", "user_email", "wallet_address", "role", "created_at", "referral_code", "referrer", "from_kol_ref_code", "invite_code").
+	query, args, err := squirrel.Select("users.username AS un", "user_email", "wallet_address", "role", "created_at", "referral_code", "referrer", "from_kol_ref_code", "invite_code", "IFNULL(credit_balance.balance, 0) AS credits").
 		From("users").LeftJoin("users_ext ON users.username = users_ext.username").
+		LeftJoin("credit_balance ON users.username = credit_balance.username").
 		Where("users.username = ?", username).ToSql()
 
 	if err != nil {
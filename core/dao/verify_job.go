@@ -0,0 +1,159 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gnasnik/titan-quest/core/generated/model"
+	"github.com/go-redis/redis/v9"
+)
+
+const (
+	verifyJobKey        = "TITAN::QUEST::VERIFY::JOB::%s"
+	verifyJobActiveKey  = "TITAN::QUEST::VERIFY::ACTIVE::%s::%d"
+	verifyJobChannel    = "TITAN::QUEST::VERIFY::DONE::%s"
+	verifyQueueKey      = "TITAN::QUEST::VERIFY::QUEUE"
+	verifyProcessingKey = "TITAN::QUEST::VERIFY::PROCESSING"
+	verifyDelayedKey    = "TITAN::QUEST::VERIFY::DELAYED"
+
+	// verifyJobExpiration 作业结果保留时间
+	verifyJobExpiration = 24 * time.Hour
+)
+
+// SaveVerifyJob 保存校验作业
+func SaveVerifyJob(ctx context.Context, job *model.VerifyJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = RedisCache.Set(ctx, fmt.Sprintf(verifyJobKey, job.ID), data, verifyJobExpiration).Result()
+	return err
+}
+
+// GetVerifyJob 获取校验作业, 不存在时返回 redis.Nil
+func GetVerifyJob(ctx context.Context, id string) (*model.VerifyJob, error) {
+	data, err := RedisCache.Get(ctx, fmt.Sprintf(verifyJobKey, id)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var out model.VerifyJob
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// AcquireActiveVerifyJob 同一用户同一任务同时只允许存在一个未结束的作业.
+// 获取成功返回 true, 否则返回已存在的作业id
+func AcquireActiveVerifyJob(ctx context.Context, username string, missionId int64, id string, ttl time.Duration) (bool, string, error) {
+	key := fmt.Sprintf(verifyJobActiveKey, username, missionId)
+
+	ok, err := RedisCache.SetNX(ctx, key, id, ttl).Result()
+	if err != nil || ok {
+		return ok, id, err
+	}
+
+	existing, err := RedisCache.Get(ctx, key).Result()
+	if err == redis.Nil {
+		// 已存在的作业恰好结束, 重新获取
+		return AcquireActiveVerifyJob(ctx, username, missionId, id, ttl)
+	}
+
+	return false, existing, err
+}
+
+// ReleaseActiveVerifyJob 作业结束后释放
+func ReleaseActiveVerifyJob(ctx context.Context, username string, missionId int64) error {
+	_, err := RedisCache.Del(ctx, fmt.Sprintf(verifyJobActiveKey, username, missionId)).Result()
+	return err
+}
+
+// EnqueueVerifyJob 将作业放入待执行队列
+func EnqueueVerifyJob(ctx context.Context, id string) error {
+	_, err := RedisCache.LPush(ctx, verifyQueueKey, id).Result()
+	return err
+}
+
+// DequeueVerifyJob 从待执行队列取出作业并放入执行中队列, 超时未取到时返回 redis.Nil
+func DequeueVerifyJob(ctx context.Context, timeout time.Duration) (string, error) {
+	return RedisCache.BLMove(ctx, verifyQueueKey, verifyProcessingKey, "RIGHT", "LEFT", timeout).Result()
+}
+
+// AckVerifyJob 作业执行结束, 从执行中队列移除
+func AckVerifyJob(ctx context.Context, id string) error {
+	_, err := RedisCache.LRem(ctx, verifyProcessingKey, 0, id).Result()
+	return err
+}
+
+// DelayVerifyJob 作业在 at 时刻后重试
+func DelayVerifyJob(ctx context.Context, id string, at time.Time) error {
+	_, err := RedisCache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, verifyDelayedKey, redis.Z{Score: float64(at.Unix()), Member: id})
+		pipe.LRem(ctx, verifyProcessingKey, 0, id)
+		return nil
+	})
+	return err
+}
+
+// PromoteDelayedVerifyJobs 将到期的重试作业放回待执行队列
+func PromoteDelayedVerifyJobs(ctx context.Context, now time.Time) (int, error) {
+	ids, err := RedisCache.ZRangeByScore(ctx, verifyDelayedKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var promoted int
+	for _, id := range ids {
+		// 多个实例同时搬运时只有删除成功的一方放回队列
+		removed, err := RedisCache.ZRem(ctx, verifyDelayedKey, id).Result()
+		if err != nil {
+			return promoted, err
+		}
+
+		if removed == 0 {
+			continue
+		}
+
+		if err := EnqueueVerifyJob(ctx, id); err != nil {
+			return promoted, err
+		}
+		promoted++
+	}
+
+	return promoted, nil
+}
+
+// GetProcessingVerifyJobs 获取执行中的作业id
+func GetProcessingVerifyJobs(ctx context.Context) ([]string, error) {
+	return RedisCache.LRange(ctx, verifyProcessingKey, 0, -1).Result()
+}
+
+// RequeueVerifyJob 将执行中的作业放回待执行队列
+func RequeueVerifyJob(ctx context.Context, id string) (bool, error) {
+	removed, err := RedisCache.LRem(ctx, verifyProcessingKey, 0, id).Result()
+	if err != nil || removed == 0 {
+		return false, err
+	}
+
+	return true, EnqueueVerifyJob(ctx, id)
+}
+
+// PublishVerifyJob 通知订阅者作业已结束
+func PublishVerifyJob(ctx context.Context, id string) error {
+	_, err := RedisCache.Publish(ctx, fmt.Sprintf(verifyJobChannel, id), id).Result()
+	return err
+}
+
+// SubscribeVerifyJob 订阅作业结束通知
+func SubscribeVerifyJob(ctx context.Context, id string) *redis.PubSub {
+	return RedisCache.Subscribe(ctx, fmt.Sprintf(verifyJobChannel, id))
+}
@@ -1,9 +1,6 @@
 package errors
 
 import (
-	"strings"
-
-	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 )
 
@@ -41,6 +38,13 @@ const (
 	WalletBound
 	CaptchaError
 
+	MissionLocked
+	MissionNotActive
+	MissionEnded
+	MissionSoldOut
+	MissionUnderReview
+	QuizAttemptsExceeded
+
 	Unknown = -1
 )
 
@@ -70,6 +74,12 @@ var ErrMap = map[int]string{
 	InvalidPublicKey:                 "invalid public key: 无效的公钥地址",
 	WalletBound:                      "wallet has been bound: 钱包已被绑定",
 	CaptchaError:                     "Slide verification failed: 滑块校验失败",
+	MissionLocked:                    "Please complete the prerequisite missions first: 请先完成前置任务",
+	MissionNotActive:                 "Mission is not available now: 任务暂未开放",
+	MissionEnded:                     "Mission has ended: 任务已结束",
+	MissionSoldOut:                   "Mission rewards have been fully claimed: 任务奖励已领完",
+	MissionUnderReview:               "Your submission is under review: 提交的内容正在审核中",
+	QuizAttemptsExceeded:             "No quiz attempts left: 答题次数已用完",
 }
 
 var (
@@ -115,17 +125,3 @@ type GenericError struct {
 func (e GenericError) Error() string {
 	return e.Err.Error()
 }
-
-func NewErrorCode(Code int, c *gin.Context) GenericError {
-	l := c.GetHeader("Lang")
-	errSplit := strings.Split(ErrMap[Code], ":")
-	var e string
-	switch l {
-	case "cn":
-		e = errSplit[1]
-	default:
-		e = errSplit[0]
-	}
-	return GenericError{Code: Code, Err: errors.New(e)}
-
-}
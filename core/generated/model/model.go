@@ -1,6 +1,11 @@
 package model
 
 import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -84,6 +89,177 @@ type UserCredit struct {
 	CreatedAt             string  `json:"created_at" db:"created_at"`
 }
 
+// githubNamePattern GitHub 用户及组织名称
+var githubNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// MissionParams 任务校验参数, 对应 mission.params 字段
+type MissionParams struct {
+	// 目标推文id
+	TweetID string `json:"tweet_id,omitempty"`
+	// 需要 tag 的用户数量
+	RequiredMentions int `json:"required_mentions,omitempty"`
+	// 推文需要包含的话题标签, 不含 #
+	RequiredHashtags []string `json:"required_hashtags,omitempty"`
+	// 推文需要包含的文本
+	RequiredText string `json:"required_text,omitempty"`
+	// 回复需要包含的关键词, 不区分大小写
+	RequiredKeywords []string `json:"required_keywords,omitempty"`
+	// 目标频道/群组id
+	TargetChannel string `json:"target_channel,omitempty"`
+	// 访问任务跳转的页面, 可以使用 {referral_code} 代表用户的邀请码
+	TargetURL string `json:"target_url,omitempty"`
+	// 访问任务需要的独立访问次数, 0 表示 1 次
+	RequiredVisits int `json:"required_visits,omitempty"`
+	// GitHub 仓库, 格式为 owner/repo
+	Repository string `json:"repository,omitempty"`
+	// GitHub 组织
+	Organization string `json:"organization,omitempty"`
+	// 答题任务每个周期可以答题的次数, 必须大于 0
+	QuizMaxAttempts int `json:"quiz_max_attempts,omitempty"`
+	// 答题任务的及格分数, 百分制, 0 表示需要全部答对
+	QuizPassingScore int `json:"quiz_passing_score,omitempty"`
+	// 答题任务是否打乱题目及选项的顺序
+	QuizShuffle bool `json:"quiz_shuffle,omitempty"`
+}
+
+// Validate 校验参数取值是否合法
+func (p *MissionParams) Validate() error {
+	if p.RequiredMentions < 0 {
+		return errors.New("required_mentions must not be negative")
+	}
+
+	for _, tag := range p.RequiredHashtags {
+		if strings.TrimSpace(strings.TrimPrefix(tag, "#")) == "" {
+			return errors.New("required_hashtags contains an empty tag")
+		}
+	}
+
+	for _, keyword := range p.RequiredKeywords {
+		if strings.TrimSpace(keyword) == "" {
+			return errors.New("required_keywords contains an empty keyword")
+		}
+	}
+
+	if p.RequiredVisits < 0 {
+		return errors.New("required_visits must not be negative")
+	}
+
+	if p.TargetURL != "" {
+		u, err := url.Parse(strings.ReplaceAll(p.TargetURL, "{referral_code}", ""))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("target_url must be an http(s) url")
+		}
+	}
+
+	if p.Repository != "" {
+		owner, repo, ok := strings.Cut(p.Repository, "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return errors.New("repository must be in the form owner/repo")
+		}
+	}
+
+	if p.Organization != "" && !githubNamePattern.MatchString(p.Organization) {
+		return errors.New("invalid organization")
+	}
+
+	if p.QuizMaxAttempts < 0 {
+		return errors.New("quiz_max_attempts must not be negative")
+	}
+
+	if p.QuizPassingScore < 0 || p.QuizPassingScore > 100 {
+		return errors.New("quiz_passing_score must be between 0 and 100")
+	}
+
+	return nil
+}
+
+// GetParams 解析任务的校验参数, 未配置时返回空参数
+func (m *Mission) GetParams() (*MissionParams, error) {
+	var params MissionParams
+	if len(m.Params) == 0 || string(m.Params) == "null" {
+		return &params, nil
+	}
+
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return &params, nil
+}
+
+// SoldOut 任务完成人次或积分预算已用完, 无法再发放 credit 积分
+func (m *Mission) SoldOut(credit int64) bool {
+	if m.MaxCompletions > 0 && m.Completions >= m.MaxCompletions {
+		return true
+	}
+	return m.CreditBudget > 0 && m.CreditsAwarded+credit > m.CreditBudget
+}
+
+// QuizOption 答题任务的选项
+type QuizOption struct {
+	Key    string `json:"key"`
+	Text   string `json:"text"`
+	TextCn string `json:"text_cn,omitempty"`
+}
+
+// GetOptions 解析题目的选项
+func (q *QuizQuestion) GetOptions() ([]*QuizOption, error) {
+	var options []*QuizOption
+	if len(q.Options) == 0 || string(q.Options) == "null" {
+		return options, nil
+	}
+
+	if err := json.Unmarshal(q.Options, &options); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// AnswerKeys 正确选项的 key
+func (q *QuizQuestion) AnswerKeys() []string {
+	var keys []string
+	for _, key := range strings.Split(q.Answer, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// VerifyJob 异步任务校验作业, 保存在 redis 中
+type VerifyJob struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	MissionID int64  `json:"mission_id"`
+	// 提交作业时任务所处的周期, 作业跨周期执行时仍按提交时的周期校验
+	StartTime string    `json:"start_time,omitempty"`
+	EndTime   string    `json:"end_time,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	ErrCode   int       `json:"err_code,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 积分流水类型
+const (
+	CreditEntryMission          = "mission"
+	CreditEntryInviteCommission = "invite_commission"
+	CreditEntryKOLCommission    = "kol_commission"
+	CreditEntryAdminAdjustment  = "admin_adjustment"
+	CreditEntryRevocation       = "revocation"
+	CreditEntryStreakBonus      = "streak_bonus"
+)
+
+// 赛季状态, 归档的赛季不再作为当前赛季, 数据仍可查询
+const (
+	SeasonStatusActive   int32 = 1
+	SeasonStatusArchived int32 = 2
+)
+
 // TableName 表名映射
 func (InviteLog) TableName() string {
 	return "invite_log"
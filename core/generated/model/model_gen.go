@@ -5,9 +5,38 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
+// 积分余额表, 由积分流水汇总
+type CreditBalance struct {
+	Username       string    `db:"username" json:"username"`
+	Balance        int64     `db:"balance" json:"balance"`
+	MissionCredits int64     `db:"mission_credits" json:"mission_credits"`
+	InviteCredits  int64     `db:"invite_credits" json:"invite_credits"`
+	KolCredits     int64     `db:"kol_credits" json:"kol_credits"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 积分流水表
+type CreditLedger struct {
+	ID int64 `db:"id" json:"id"`
+	// 积分账户, 用户名或 KOL 用户id
+	Username string `db:"username" json:"username"`
+	// 流水类型
+	Type string `db:"type" json:"type"`
+	// 关联的记录id
+	RefID string `db:"ref_id" json:"ref_id"`
+	// 变动积分, 扣减为负数
+	Amount int64 `db:"amount" json:"amount"`
+	// 变动后的余额
+	Balance   int64     `db:"balance" json:"balance"`
+	Reason    string    `db:"reason" json:"reason"`
+	Operator  string    `db:"operator" json:"operator"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
 type DiscordOauth struct {
 	ID            int64     `db:"id" json:"id"`
 	State         string    `db:"state" json:"state"`
@@ -19,6 +48,19 @@ type DiscordOauth struct {
 	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
 }
 
+type GithubOauth struct {
+	ID           int64  `db:"id" json:"id"`
+	State        string `db:"state" json:"state"`
+	Username     string `db:"username" json:"username"`
+	GithubUserID string `db:"github_user_id" json:"github_user_id"`
+	GithubLogin  string `db:"github_login" json:"github_login"`
+	// 用户授权的 token, 校验 star、fork 等任务时使用
+	AccessToken string    `db:"access_token" json:"-"`
+	RedirectUri string    `db:"redirect_uri" json:"redirect_uri"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
 // 邀请明细表
 type InviteLog struct {
 	// 主键id
@@ -31,6 +73,8 @@ type InviteLog struct {
 	MissionID int64 `db:"mission_id" json:"mission_id"`
 	// 积分
 	Credit int64 `db:"credit" json:"credit"`
+	// 被邀请人的任务完成记录id
+	UserMissionID int64 `db:"user_mission_id" json:"user_mission_id"`
 	// 创建时间
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
@@ -82,22 +126,38 @@ type LoginLog struct {
 }
 
 type Mission struct {
-	ID        int64     `db:"id" json:"id"`
-	Title     string    `db:"title" json:"title"`
-	TitleCn   string    `db:"title_cn" json:"title_cn"`
-	Channel   string    `db:"channel" json:"channel"`
-	Logo      string    `db:"logo" json:"logo"`
-	Credit    int64     `db:"credit" json:"credit"`
-	Status    int32     `db:"status" json:"status"`
-	OpenUrl   string    `db:"open_url" json:"open_url"`
-	TargetID  string    `db:"target_id" json:"target_id"`
-	StartTime time.Time `db:"start_time" json:"start_time"`
-	EndTime   time.Time `db:"end_time" json:"end_time"`
-	Type      int32     `db:"type" json:"type"`
-	SortID    int32     `db:"sort_id" json:"sort_id"`
-	ParentID  int64     `db:"parent_id" json:"parent_id"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID         int64           `db:"id" json:"id"`
+	Title      string          `db:"title" json:"title"`
+	TitleCn    string          `db:"title_cn" json:"title_cn"`
+	Channel    string          `db:"channel" json:"channel"`
+	Logo       string          `db:"logo" json:"logo"`
+	Credit     int64           `db:"credit" json:"credit"`
+	Status     int32           `db:"status" json:"status"`
+	OpenUrl    string          `db:"open_url" json:"open_url"`
+	TargetID   string          `db:"target_id" json:"target_id"`
+	Verifier   string          `db:"verifier" json:"verifier"`
+	Params     json.RawMessage `db:"params" json:"params"`
+	StartTime  time.Time       `db:"start_time" json:"start_time"`
+	EndTime    time.Time       `db:"end_time" json:"end_time"`
+	Type       int32           `db:"type" json:"type"`
+	Recurrence string          `db:"recurrence" json:"recurrence"`
+	SortID     int32           `db:"sort_id" json:"sort_id"`
+	ParentID   int64           `db:"parent_id" json:"parent_id"`
+	// 最多完成人次及积分总预算, 0 表示不限
+	MaxCompletions int64     `db:"max_completions" json:"max_completions"`
+	CreditBudget   int64     `db:"credit_budget" json:"credit_budget"`
+	Completions    int64     `db:"completions" json:"completions"`
+	CreditsAwarded int64     `db:"credits_awarded" json:"credits_awarded"`
+	SeasonID       int64     `db:"season_id" json:"season_id"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type MissionPrerequisite struct {
+	ID             int64     `db:"id" json:"id"`
+	MissionID      int64     `db:"mission_id" json:"mission_id"`
+	PrerequisiteID int64     `db:"prerequisite_id" json:"prerequisite_id"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
 }
 
 type OperationLog struct {
@@ -120,22 +180,31 @@ type OperationLog struct {
 }
 
 type SubMission struct {
-	ID        int64     `db:"id" json:"id"`
-	Title     string    `db:"title" json:"title"`
-	TitleCn   string    `db:"title_cn" json:"title_cn"`
-	Channel   string    `db:"channel" json:"channel"`
-	Logo      string    `db:"logo" json:"logo"`
-	Credit    int64     `db:"credit" json:"credit"`
-	Status    int32     `db:"status" json:"status"`
-	OpenUrl   string    `db:"open_url" json:"open_url"`
-	TargetID  string    `db:"target_id" json:"target_id"`
-	StartTime time.Time `db:"start_time" json:"start_time"`
-	EndTime   time.Time `db:"end_time" json:"end_time"`
-	Type      int32     `db:"type" json:"type"`
-	SortID    int32     `db:"sort_id" json:"sort_id"`
-	ParentID  int64     `db:"parent_id" json:"parent_id"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID         int64           `db:"id" json:"id"`
+	Title      string          `db:"title" json:"title"`
+	TitleCn    string          `db:"title_cn" json:"title_cn"`
+	Channel    string          `db:"channel" json:"channel"`
+	Logo       string          `db:"logo" json:"logo"`
+	Credit     int64           `db:"credit" json:"credit"`
+	Status     int32           `db:"status" json:"status"`
+	OpenUrl    string          `db:"open_url" json:"open_url"`
+	TargetID   string          `db:"target_id" json:"target_id"`
+	Verifier   string          `db:"verifier" json:"verifier"`
+	Params     json.RawMessage `db:"params" json:"params"`
+	StartTime  time.Time       `db:"start_time" json:"start_time"`
+	EndTime    time.Time       `db:"end_time" json:"end_time"`
+	Type       int32           `db:"type" json:"type"`
+	Recurrence string          `db:"recurrence" json:"recurrence"`
+	SortID     int32           `db:"sort_id" json:"sort_id"`
+	ParentID   int64           `db:"parent_id" json:"parent_id"`
+	// 最多完成人次及积分总预算, 0 表示不限
+	MaxCompletions int64     `db:"max_completions" json:"max_completions"`
+	CreditBudget   int64     `db:"credit_budget" json:"credit_budget"`
+	Completions    int64     `db:"completions" json:"completions"`
+	CreditsAwarded int64     `db:"credits_awarded" json:"credits_awarded"`
+	SeasonID       int64     `db:"season_id" json:"season_id"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
 }
 
 type TelegramOauth struct {
@@ -190,10 +259,147 @@ type UserMission struct {
 	Type         int32     `db:"type" json:"type"`
 	Credit       int64     `db:"credit" json:"credit"`
 	Content      string    `db:"content" json:"content"`
+	Period       string    `db:"period" json:"period"`
+	SeasonID     int64     `db:"season_id" json:"season_id"`
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
 }
 
+// 赛季
+type Season struct {
+	ID        int64     `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	NameCn    string    `db:"name_cn" json:"name_cn"`
+	Status    int32     `db:"status" json:"status"`
+	StartTime time.Time `db:"start_time" json:"start_time"`
+	EndTime   time.Time `db:"end_time" json:"end_time"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 用户赛季积分
+type SeasonCredit struct {
+	SeasonID       int64     `db:"season_id" json:"season_id"`
+	Username       string    `db:"username" json:"username"`
+	Credits        int64     `db:"credits" json:"credits"`
+	MissionCredits int64     `db:"mission_credits" json:"mission_credits"`
+	InviteCredits  int64     `db:"invite_credits" json:"invite_credits"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 人工审核任务的用户提交记录
+type MissionSubmission struct {
+	ID        int64           `db:"id" json:"id"`
+	Username  string          `db:"username" json:"username"`
+	MissionID int64           `db:"mission_id" json:"mission_id"`
+	Period    string          `db:"period" json:"period"`
+	Urls      json.RawMessage `db:"urls" json:"urls"`
+	Content   string          `db:"content" json:"content"`
+	Status    int32           `db:"status" json:"status"`
+	Reason    string          `db:"reason" json:"reason"`
+	Reviewer  string          `db:"reviewer" json:"reviewer"`
+	// 审核通过后的任务完成记录id
+	UserMissionID int64     `db:"user_mission_id" json:"user_mission_id"`
+	ReviewedAt    time.Time `db:"reviewed_at" json:"reviewed_at"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 答题任务的题目
+type QuizQuestion struct {
+	ID         int64           `db:"id" json:"id"`
+	MissionID  int64           `db:"mission_id" json:"mission_id"`
+	Question   string          `db:"question" json:"question"`
+	QuestionCn string          `db:"question_cn" json:"question_cn"`
+	Options    json.RawMessage `db:"options" json:"options"`
+	// 正确选项的 key, 多选题用逗号分隔
+	Answer    string    `db:"answer" json:"answer"`
+	SortID    int32     `db:"sort_id" json:"sort_id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 用户的答题记录
+type QuizAttempt struct {
+	ID        int64  `db:"id" json:"id"`
+	Username  string `db:"username" json:"username"`
+	MissionID int64  `db:"mission_id" json:"mission_id"`
+	Period    string `db:"period" json:"period"`
+	// 得分, 百分制
+	Score     int64           `db:"score" json:"score"`
+	Passed    bool            `db:"passed" json:"passed"`
+	Answers   json.RawMessage `db:"answers" json:"answers"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// 访问任务追踪链接的独立访问记录
+type TrackingVisit struct {
+	ID          int64     `db:"id" json:"id"`
+	Username    string    `db:"username" json:"username"`
+	MissionID   int64     `db:"mission_id" json:"mission_id"`
+	Period      string    `db:"period" json:"period"`
+	Ip          string    `db:"ip" json:"ip"`
+	Fingerprint string    `db:"fingerprint" json:"fingerprint"`
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// 任务渠道, 任务的 channel 字段对应渠道的 code
+type Channel struct {
+	ID        int64     `db:"id" json:"id"`
+	Code      string    `db:"code" json:"code"`
+	Group     string    `db:"group_name" json:"group"`
+	Title     string    `db:"title" json:"title"`
+	TitleCn   string    `db:"title_cn" json:"title_cn"`
+	Icon      string    `db:"icon" json:"icon"`
+	SortID    int32     `db:"sort_id" json:"sort_id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 多语言文案, 覆盖或补充内置文案
+type Translation struct {
+	ID        int64     `db:"id" json:"id"`
+	Locale    string    `db:"locale" json:"locale"`
+	Key       string    `db:"msg_key" json:"key"`
+	Text      string    `db:"text" json:"text"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 用户每日任务的连续完成记录
+type UserStreak struct {
+	Username      string    `db:"username" json:"-"`
+	MissionID     int64     `db:"mission_id" json:"mission_id"`
+	CurrentStreak int64     `db:"current_streak" json:"current_streak"`
+	LongestStreak int64     `db:"longest_streak" json:"longest_streak"`
+	LastDay       string    `db:"last_day" json:"last_day"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 任务完成记录的重新校验状态
+type UserMissionRecheck struct {
+	UserMissionID int64     `db:"user_mission_id" json:"user_mission_id"`
+	Username      string    `db:"username" json:"username"`
+	MissionID     int64     `db:"mission_id" json:"mission_id"`
+	Status        string    `db:"status" json:"status"`
+	Reason        string    `db:"reason" json:"reason"`
+	CheckedAt     time.Time `db:"checked_at" json:"checked_at"`
+	FailedAt      time.Time `db:"failed_at" json:"failed_at"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// 用户站内通知
+type UserNotification struct {
+	ID        int64           `db:"id" json:"id"`
+	Username  string          `db:"username" json:"username"`
+	Type      string          `db:"type" json:"type"`
+	Params    json.RawMessage `db:"params" json:"params"`
+	Read      bool            `db:"is_read" json:"read"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
 type UserTwitterLink struct {
 	ID        int64     `db:"id" json:"id"`
 	Username  string    `db:"username" json:"username"`
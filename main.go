@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -36,10 +37,20 @@ func main() {
 
 	api.InitCaptcha()
 
+	api.InitI18n(context.Background(), &cfg)
+
+	api.StartMissionCatalogSync(context.Background())
+
 	go api.ServerAPI(&cfg)
 
 	api.InitBot()
 
+	api.StartVerifyWorkers(context.Background(), &cfg)
+
+	api.StartReverifyJob(context.Background(), &cfg)
+
+	api.StartHashtagSearchJob(context.Background(), &cfg)
+
 	signal.Notify(OsSignal, syscall.SIGINT, syscall.SIGTERM)
 	_ = <-OsSignal
 
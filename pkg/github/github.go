@@ -0,0 +1,168 @@
+// Package github 封装任务校验用到的 GitHub REST 接口, 请求使用用户 OAuth 授权的 token
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultBaseURL = "https://api.github.com"
+
+// Client GitHub 接口, 测试时可以替换为指向本地服务的实现
+type Client interface {
+	// User 获取 token 对应的用户
+	User(ctx context.Context, token string) (*User, error)
+	// IsStarred 用户是否 star 了仓库
+	IsStarred(ctx context.Context, token, owner, repo string) (bool, error)
+	// HasForked 用户是否 fork 了仓库, fork 后的仓库需要保留原名称
+	HasForked(ctx context.Context, token, login, owner, repo string) (bool, error)
+	// HasMergedPullRequest 用户是否在组织的仓库中有已合并的 PR
+	HasMergedPullRequest(ctx context.Context, token, login, org string) (bool, error)
+}
+
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Email string `json:"email"`
+}
+
+// Error GitHub 接口返回的错误
+type Error struct {
+	StatusCode int
+	Message    string
+	// RateLimited 触发了限流, GitHub 限流时返回 403 或 429
+	RateLimited bool
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("github: %d %s", e.StatusCode, e.Message)
+}
+
+// Temporary 限流及服务端错误可以稍后重试
+func (e *Error) Temporary() bool {
+	return e.RateLimited || e.StatusCode >= http.StatusInternalServerError
+}
+
+type client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient 创建 GitHub 客户端, baseURL 为空时使用 api.github.com
+func NewClient(baseURL string, httpClient *http.Client) Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// do 发送请求, 返回状态码, out 不为空时解析 200 响应的内容, 其他非 404 的错误状态码返回 *Error
+func (c *client) do(ctx context.Context, token, path string, out interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if out != nil {
+			return resp.StatusCode, json.Unmarshal(body, out)
+		}
+		return resp.StatusCode, nil
+	case http.StatusNoContent, http.StatusNotFound:
+		return resp.StatusCode, nil
+	}
+
+	var msg struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &msg)
+
+	return resp.StatusCode, &Error{
+		StatusCode: resp.StatusCode,
+		Message:    msg.Message,
+		RateLimited: resp.StatusCode == http.StatusTooManyRequests ||
+			(resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"),
+	}
+}
+
+func (c *client) User(ctx context.Context, token string) (*User, error) {
+	var out User
+	code, err := c.do(ctx, token, "/user", &out)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, &Error{StatusCode: code, Message: "user not found"}
+	}
+
+	return &out, nil
+}
+
+func (c *client) IsStarred(ctx context.Context, token, owner, repo string) (bool, error) {
+	code, err := c.do(ctx, token, fmt.Sprintf("/user/starred/%s/%s", url.PathEscape(owner), url.PathEscape(repo)), nil)
+	if err != nil {
+		return false, err
+	}
+
+	return code == http.StatusNoContent, nil
+}
+
+func (c *client) HasForked(ctx context.Context, token, login, owner, repo string) (bool, error) {
+	var out struct {
+		Fork   bool `json:"fork"`
+		Parent struct {
+			FullName string `json:"full_name"`
+		} `json:"parent"`
+	}
+
+	code, err := c.do(ctx, token, fmt.Sprintf("/repos/%s/%s", url.PathEscape(login), url.PathEscape(repo)), &out)
+	if err != nil || code != http.StatusOK {
+		return false, err
+	}
+
+	return out.Fork && strings.EqualFold(out.Parent.FullName, owner+"/"+repo), nil
+}
+
+func (c *client) HasMergedPullRequest(ctx context.Context, token, login, org string) (bool, error) {
+	var out struct {
+		TotalCount int64 `json:"total_count"`
+	}
+
+	q := fmt.Sprintf("is:pr is:merged author:%s org:%s", login, org)
+	code, err := c.do(ctx, token, "/search/issues?per_page=1&q="+url.QueryEscape(q), &out)
+	if err != nil || code != http.StatusOK {
+		return false, err
+	}
+
+	return out.TotalCount > 0, nil
+}
@@ -0,0 +1,98 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":42,"login":"octocat","email":"octocat@github.com"}`))
+	})
+	mux.HandleFunc("/user/starred/Titannet-dao/titan-node", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/repos/octocat/titan-node", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"fork":true,"parent":{"full_name":"Titannet-dao/titan-node"}}`))
+	})
+	mux.HandleFunc("/repos/octocat/titan-explorer", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"fork":false}`))
+	})
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "is:pr is:merged author:octocat org:Titannet-dao":
+			_, _ = w.Write([]byte(`{"total_count":3}`))
+		case "is:pr is:merged author:octocat org:limited":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+		default:
+			_, _ = w.Write([]byte(`{"total_count":0}`))
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newFakeServer(t)
+	client := NewClient(server.URL, server.Client())
+	ctx := context.Background()
+
+	u, err := client.User(ctx, "token")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), u.ID)
+	require.Equal(t, "octocat", u.Login)
+
+	_, err = client.User(ctx, "invalid")
+	var ge *Error
+	require.ErrorAs(t, err, &ge)
+	require.Equal(t, http.StatusUnauthorized, ge.StatusCode)
+	require.False(t, ge.Temporary())
+
+	starred, err := client.IsStarred(ctx, "token", "Titannet-dao", "titan-node")
+	require.NoError(t, err)
+	require.True(t, starred)
+
+	starred, err = client.IsStarred(ctx, "token", "Titannet-dao", "titan-explorer")
+	require.NoError(t, err)
+	require.False(t, starred)
+
+	forked, err := client.HasForked(ctx, "token", "octocat", "Titannet-dao", "titan-node")
+	require.NoError(t, err)
+	require.True(t, forked)
+
+	// a repository with the same name that is not a fork
+	forked, err = client.HasForked(ctx, "token", "octocat", "Titannet-dao", "titan-explorer")
+	require.NoError(t, err)
+	require.False(t, forked)
+
+	forked, err = client.HasForked(ctx, "token", "octocat", "Titannet-dao", "titan-storage")
+	require.NoError(t, err)
+	require.False(t, forked)
+
+	merged, err := client.HasMergedPullRequest(ctx, "token", "octocat", "Titannet-dao")
+	require.NoError(t, err)
+	require.True(t, merged)
+
+	merged, err = client.HasMergedPullRequest(ctx, "token", "octocat", "other")
+	require.NoError(t, err)
+	require.False(t, merged)
+
+	_, err = client.HasMergedPullRequest(ctx, "token", "octocat", "limited")
+	require.ErrorAs(t, err, &ge)
+	require.True(t, ge.RateLimited)
+	require.True(t, ge.Temporary())
+}
@@ -0,0 +1,251 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// aliases maps language tags used by clients to the locale names of the catalog.
+// The clients have always sent "cn" for simplified Chinese.
+var aliases = map[string]string{
+	"zh":      "cn",
+	"zh-cn":   "cn",
+	"zh-hans": "cn",
+	"zh-sg":   "cn",
+}
+
+// Catalog is a concurrency safe message catalog keyed by locale and message key.
+// Builtin messages are compiled in, loaded messages (e.g. from the database)
+// override them and may add new locales.
+type Catalog struct {
+	fallback string
+
+	mu       sync.RWMutex
+	builtin  map[string]map[string]string
+	messages map[string]map[string]string
+}
+
+// NewCatalog returns an empty catalog falling back to the fallback locale.
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		fallback: Normalize(fallback),
+		builtin:  make(map[string]map[string]string),
+		messages: make(map[string]map[string]string),
+	}
+}
+
+// Fallback returns the locale used when no requested locale is available.
+func (c *Catalog) Fallback() string {
+	return c.fallback
+}
+
+// Set adds a builtin message.
+func (c *Catalog) Set(locale, key, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set(c.builtin, Normalize(locale), key, text)
+	set(c.messages, Normalize(locale), key, text)
+}
+
+// Load replaces the loaded messages, builtin messages are kept unless overridden.
+func (c *Catalog) Load(messages map[string]map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make(map[string]map[string]string)
+	for locale, m := range c.builtin {
+		for key, text := range m {
+			set(out, locale, key, text)
+		}
+	}
+	for locale, m := range messages {
+		for key, text := range m {
+			set(out, Normalize(locale), key, text)
+		}
+	}
+	c.messages = out
+}
+
+func set(m map[string]map[string]string, locale, key, text string) {
+	if m[locale] == nil {
+		m[locale] = make(map[string]string)
+	}
+	m[locale][key] = text
+}
+
+// Locales returns the locales having at least one message.
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		out = append(out, locale)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (c *Catalog) hasLocale(locale string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.messages[locale]) > 0
+}
+
+// Get returns the message of key in exactly locale.
+func (c *Catalog) Get(locale, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	text, ok := c.messages[Normalize(locale)][key]
+	return text, ok
+}
+
+// Lookup returns the message of key in locale, falling back to the base language
+// and then to the fallback locale.
+func (c *Catalog) Lookup(locale, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, l := range Chain(locale, c.fallback) {
+		if text, ok := c.messages[l][key]; ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// Text returns the message of key in locale, or key itself when it is missing.
+func (c *Catalog) Text(locale, key string) string {
+	if text, ok := c.Lookup(locale, key); ok {
+		return text
+	}
+	return key
+}
+
+// Format returns the message of key in locale with every {name} placeholder
+// replaced by args[name].
+func (c *Catalog) Format(locale, key string, args map[string]string) string {
+	text := c.Text(locale, key)
+	if len(args) == 0 {
+		return text
+	}
+
+	pairs := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Resolve picks the locale of a request. The explicit lang (the Lang header)
+// wins, then the languages of the Accept-Language header by quality. Each
+// candidate matches exactly or by its base language, the fallback locale is
+// returned when nothing matches.
+func (c *Catalog) Resolve(lang, acceptLanguage string) string {
+	candidates := append([]string{lang}, ParseAcceptLanguage(acceptLanguage)...)
+	for _, candidate := range candidates {
+		locale := Normalize(candidate)
+		if locale == "" {
+			continue
+		}
+		if c.hasLocale(locale) {
+			return locale
+		}
+		if base := Base(locale); base != locale && c.hasLocale(base) {
+			return base
+		}
+	}
+	return c.fallback
+}
+
+// Normalize lower-cases a language tag, uses "-" as separator and maps aliases.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, "_", "-")))
+	if alias, ok := aliases[tag]; ok {
+		return alias
+	}
+	return tag
+}
+
+// Base returns the base language of a locale, e.g. "pt" for "pt-br".
+func Base(locale string) string {
+	if i := strings.Index(locale, "-"); i > 0 {
+		base := locale[:i]
+		if alias, ok := aliases[base]; ok {
+			return alias
+		}
+		return base
+	}
+	return locale
+}
+
+// Chain returns the locales to look a message up in, most specific first.
+func Chain(locale, fallback string) []string {
+	locale = Normalize(locale)
+	out := make([]string, 0, 3)
+	for _, l := range []string{locale, Base(locale), fallback} {
+		if l == "" || contains(out, l) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header ordered
+// by quality, languages with q=0 and the wildcard are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if !strings.HasPrefix(field, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(field, "q="), 64)
+			if err == nil {
+				quality = q
+			}
+		}
+
+		if quality <= 0 {
+			continue
+		}
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	out := make([]string, 0, len(languages))
+	for _, l := range languages {
+		out = append(out, l.tag)
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	c := NewCatalog("en")
+	c.Set("en", "hello", "Hello")
+	c.Set("cn", "hello", "你好")
+	c.Load(map[string]map[string]string{"ko": {"hello": "안녕하세요"}})
+
+	cases := []struct {
+		lang, accept, want string
+	}{
+		{"", "", "en"},
+		{"cn", "ko", "cn"},
+		{"", "zh-CN,zh;q=0.9", "cn"},
+		{"", "fr;q=0.9, ko-KR;q=0.8", "ko"},
+		{"", "ru, ko;q=0", "en"},
+		{"vi", "", "en"},
+	}
+
+	for _, tc := range cases {
+		if got := c.Resolve(tc.lang, tc.accept); got != tc.want {
+			t.Fatalf("Resolve(%q, %q) = %q, want %q", tc.lang, tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c := NewCatalog("en")
+	c.Set("en", "credits", "{credit} credits of {title}")
+	c.Set("en", "only_en", "English")
+	c.Load(map[string]map[string]string{
+		"pt":    {"credits": "{credit} créditos de {title}"},
+		"pt-BR": {"only_en": "Português"},
+	})
+
+	if got := c.Format("pt-br", "credits", map[string]string{"credit": "5", "title": "Follow"}); got != "5 créditos de Follow" {
+		t.Fatalf("unexpected message %q", got)
+	}
+	if got := c.Text("pt-br", "only_en"); got != "Português" {
+		t.Fatalf("unexpected message %q", got)
+	}
+	if got := c.Text("ko", "only_en"); got != "English" {
+		t.Fatalf("expected fallback message, got %q", got)
+	}
+	if got := c.Text("ko", "missing"); got != "missing" {
+		t.Fatalf("expected key for missing message, got %q", got)
+	}
+
+	// reloading keeps the builtin messages
+	c.Load(nil)
+	if got := c.Locales(); !reflect.DeepEqual(got, []string{"en"}) {
+		t.Fatalf("unexpected locales %v", got)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("en-US;q=0.5, *;q=0.1, vi, ru;q=0.8")
+	want := []string{"vi", "ru", "en-US"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
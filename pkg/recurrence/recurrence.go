@@ -0,0 +1,116 @@
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-module/carbon/v2"
+)
+
+const (
+	Once    = "once"
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+
+	cronPrefix = "cron:"
+)
+
+// Window is the period [Start, End) a recurring mission can be completed in.
+// A zero Start and End means the mission never resets.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Unbounded reports whether the window covers all time.
+func (w Window) Unbounded() bool {
+	return w.Start.IsZero() && w.End.IsZero()
+}
+
+// Contains reports whether t falls inside the window.
+func (w Window) Contains(t time.Time) bool {
+	if !w.Start.IsZero() && t.Before(w.Start) {
+		return false
+	}
+	if !w.End.IsZero() && !t.Before(w.End) {
+		return false
+	}
+	return true
+}
+
+// Rule computes the period window for a point in time.
+type Rule interface {
+	Window(t time.Time) Window
+	String() string
+}
+
+// Parse parses a recurrence rule: once, daily, weekly, monthly or a five-field
+// cron expression optionally prefixed with "cron:". An empty string means once.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+
+	switch strings.ToLower(s) {
+	case "", Once:
+		return onceRule{}, nil
+	case Daily:
+		return dailyRule{}, nil
+	case Weekly:
+		return weeklyRule{}, nil
+	case Monthly:
+		return monthlyRule{}, nil
+	}
+
+	expr := strings.TrimSpace(strings.TrimPrefix(s, cronPrefix))
+	sched, err := parseCron(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence %q: %w", s, err)
+	}
+
+	return cronRule{expr: expr, sched: sched}, nil
+}
+
+type onceRule struct{}
+
+func (onceRule) Window(time.Time) Window { return Window{} }
+func (onceRule) String() string          { return Once }
+
+type dailyRule struct{}
+
+func (dailyRule) Window(t time.Time) Window {
+	start := carbon.CreateFromStdTime(t).StartOfDay()
+	return Window{Start: start.StdTime(), End: start.AddDay().StdTime()}
+}
+
+func (dailyRule) String() string { return Daily }
+
+type weeklyRule struct{}
+
+func (weeklyRule) Window(t time.Time) Window {
+	start := carbon.CreateFromStdTime(t).StartOfWeek()
+	return Window{Start: start.StdTime(), End: start.AddWeek().StdTime()}
+}
+
+func (weeklyRule) String() string { return Weekly }
+
+type monthlyRule struct{}
+
+func (monthlyRule) Window(t time.Time) Window {
+	start := carbon.CreateFromStdTime(t).StartOfMonth()
+	return Window{Start: start.StdTime(), End: start.AddMonthNoOverflow().StdTime()}
+}
+
+func (monthlyRule) String() string { return Monthly }
+
+type cronRule struct {
+	expr  string
+	sched *schedule
+}
+
+// Window returns the period between the last fire time at or before t and the next one after t.
+func (r cronRule) Window(t time.Time) Window {
+	return Window{Start: r.sched.prev(t), End: r.sched.next(t)}
+}
+
+func (r cronRule) String() string { return cronPrefix + r.expr }
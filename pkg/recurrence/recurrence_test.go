@@ -0,0 +1,61 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, s string) Rule {
+	r, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		rule       string
+		start, end time.Time
+	}{
+		{Daily, time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)},
+		{Weekly, time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC)},
+		{Monthly, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		// every Monday at 08:00
+		{"cron:0 8 * * 1", time.Date(2024, 5, 13, 8, 0, 0, 0, time.UTC), time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)},
+		// every 6 hours
+		{"0 */6 * * *", time.Date(2024, 5, 15, 6, 0, 0, 0, time.UTC), time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)},
+		// 1st and 15th of each month
+		{"cron:0 0 1,15 * *", time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		w := mustParse(t, c.rule).Window(now)
+		if !w.Start.Equal(c.start) || !w.End.Equal(c.end) {
+			t.Errorf("%s: got [%s, %s), want [%s, %s)", c.rule, w.Start, w.End, c.start, c.end)
+		}
+		if !w.Contains(now) {
+			t.Errorf("%s: window does not contain now", c.rule)
+		}
+	}
+}
+
+func TestOnce(t *testing.T) {
+	for _, s := range []string{"", Once} {
+		w := mustParse(t, s).Window(time.Now())
+		if !w.Unbounded() || !w.Contains(time.Time{}) {
+			t.Errorf("%q: expected unbounded window", s)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{"hourly", "cron:* * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package streak
+
+import (
+	"sort"
+	"time"
+)
+
+// DayLayout is the layout of a streak day.
+const DayLayout = "2006-01-02"
+
+// Day returns the calendar day of t in loc.
+func Day(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format(DayLayout)
+}
+
+// State is a user's streak on a daily mission.
+type State struct {
+	Current int64
+	Longest int64
+	LastDay string
+}
+
+// Advance returns the state after completing the mission on day. Completing on
+// the day after LastDay extends the streak, completing again on LastDay keeps it,
+// anything else starts a new streak.
+func (s State) Advance(day string) State {
+	switch {
+	case s.LastDay == day:
+		return s
+	case s.LastDay != "" && nextDay(s.LastDay) == day:
+		s.Current++
+	default:
+		s.Current = 1
+	}
+
+	s.LastDay = day
+	if s.Current > s.Longest {
+		s.Longest = s.Current
+	}
+	return s
+}
+
+// Active returns the current streak as seen on day: a streak whose last
+// completion is older than yesterday has been broken.
+func (s State) Active(day string) int64 {
+	if s.LastDay == day || nextDay(s.LastDay) == day {
+		return s.Current
+	}
+	return 0
+}
+
+func nextDay(day string) string {
+	t, err := time.Parse(DayLayout, day)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, 1).Format(DayLayout)
+}
+
+// Tier grants Percent extra credits once the streak reaches Days.
+type Tier struct {
+	Days    int64
+	Percent int64
+}
+
+// BonusPercent returns the percent of the highest tier reached by streak.
+func BonusPercent(tiers []Tier, streak int64) int64 {
+	sorted := make([]Tier, len(tiers))
+	copy(sorted, tiers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Days < sorted[j].Days })
+
+	var percent int64
+	for _, t := range sorted {
+		if streak < t.Days {
+			break
+		}
+		percent = t.Percent
+	}
+	return percent
+}
+
+// Bonus returns the extra credits for completing a mission worth credit on the given streak.
+func Bonus(tiers []Tier, streak, credit int64) int64 {
+	return credit * BonusPercent(tiers, streak) / 100
+}
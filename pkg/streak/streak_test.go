@@ -0,0 +1,66 @@
+package streak
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvance(t *testing.T) {
+	var s State
+
+	s = s.Advance("2024-05-30")
+	s = s.Advance("2024-05-31")
+	// completing twice on the same day keeps the streak
+	s = s.Advance("2024-05-31")
+	s = s.Advance("2024-06-01")
+	if s.Current != 3 || s.Longest != 3 {
+		t.Fatalf("expected streak 3, got %+v", s)
+	}
+
+	if got := s.Active("2024-06-02"); got != 3 {
+		t.Fatalf("expected active streak 3 on the next day, got %d", got)
+	}
+
+	if got := s.Active("2024-06-03"); got != 0 {
+		t.Fatalf("expected broken streak, got %d", got)
+	}
+
+	s = s.Advance("2024-06-03")
+	if s.Current != 1 || s.Longest != 3 {
+		t.Fatalf("expected new streak, got %+v", s)
+	}
+}
+
+func TestDay(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	ts := time.Date(2024, 5, 15, 20, 0, 0, 0, time.UTC)
+
+	if got := Day(ts, loc); got != "2024-05-16" {
+		t.Fatalf("expected 2024-05-16, got %s", got)
+	}
+
+	if got := Day(ts, time.UTC); got != "2024-05-15" {
+		t.Fatalf("expected 2024-05-15, got %s", got)
+	}
+}
+
+func TestBonus(t *testing.T) {
+	tiers := []Tier{{Days: 30, Percent: 25}, {Days: 7, Percent: 10}}
+
+	cases := []struct {
+		streak, bonus int64
+	}{
+		{1, 0},
+		{6, 0},
+		{7, 10},
+		{29, 10},
+		{30, 25},
+		{100, 25},
+	}
+
+	for _, c := range cases {
+		if got := Bonus(tiers, c.streak, 100); got != c.bonus {
+			t.Errorf("streak %d: expected bonus %d, got %d", c.streak, c.bonus, got)
+		}
+	}
+}
@@ -122,11 +122,19 @@ CREATE TABLE `mission` (
 `status` int(1) NOT NULL DEFAULT 0,
 `open_url` text NOT NULL,
 `target_id` varchar(128) NOT NULL DEFAULT '',
+`verifier` varchar(64) NOT NULL DEFAULT '',
+`params` json DEFAULT NULL,
 `start_time` datetime NOT NULL DEFAULT 0,
 `end_time` datetime NOT NULL DEFAULT 0,
 `type` int(4) NOT NULL DEFAULT 0,
+`recurrence` varchar(64) NOT NULL DEFAULT '',
 `sort_id` int(4) NOT NULL DEFAULT 0,
 `parent_id` bigint(20) NOT NULL DEFAULT 0,
+`max_completions` bigint(20) NOT NULL DEFAULT 0 COMMENT '最多完成人次, 0 不限',
+`credit_budget` bigint(20) NOT NULL DEFAULT 0 COMMENT '积分总预算, 0 不限',
+`completions` bigint(20) NOT NULL DEFAULT 0 COMMENT '已完成人次',
+`credits_awarded` bigint(20) NOT NULL DEFAULT 0 COMMENT '已发放积分',
+`season_id` bigint(20) NOT NULL DEFAULT 0 COMMENT '所属赛季, 0 不属于任何赛季',
 `created_at` datetime NOT NULL DEFAULT 0,
 `updated_at` datetime NOT NULL DEFAULT 0,
 PRIMARY KEY (`id`)
@@ -136,6 +144,16 @@ PRIMARY KEY (`id`)
 create table sub_mission like mission;
 
 
+CREATE TABLE `mission_prerequisite` (
+`id` bigint(20) NOT NULL AUTO_INCREMENT,
+`mission_id` bigint(20) NOT NULL DEFAULT 0,
+`prerequisite_id` bigint(20) NOT NULL DEFAULT 0,
+`created_at` datetime NOT NULL DEFAULT 0,
+PRIMARY KEY (`id`),
+UNIQUE KEY `uniq_mission_prerequisite` (`mission_id`, `prerequisite_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
 CREATE TABLE `user_mission` (
 `id` bigint(20) NOT NULL AUTO_INCREMENT,
 `username` varchar(128) NOT NULL DEFAULT '',
@@ -144,9 +162,13 @@ CREATE TABLE `user_mission` (
 `type` int(4) NOT NULL DEFAULT 0,
 `credit` bigint(20) NOT NULL DEFAULT 0,
 `content` varchar(128) NOT NULL DEFAULT '',
+`period` varchar(255) NOT NULL DEFAULT '',
+`season_id` bigint(20) NOT NULL DEFAULT 0,
 `created_at` datetime NOT NULL DEFAULT 0,
 `updated_at` datetime NOT NULL DEFAULT 0,
-PRIMARY KEY (`id`)
+PRIMARY KEY (`id`),
+UNIQUE KEY `uniq_user_mission_period` (`username`, `mission_id`, `sub_mission_id`, `period`),
+KEY `idx_season_id` (`season_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 
@@ -212,6 +234,199 @@ CREATE TABLE IF NOT EXISTS `invite_log` (
   `invited_name` varchar(255) NOT NULL DEFAULT '' COMMENT '被邀请人名字',
   `mission_id` bigint(20) NOT NULL DEFAULT 0 COMMENT '被邀请人完成的任务',
   `credit` bigint(20) NOT NULL DEFAULT 0 COMMENT '积分',
+  `user_mission_id` bigint(20) NOT NULL DEFAULT 0 COMMENT '被邀请人的任务完成记录',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  KEY `idx_user_mission_id` (`user_mission_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='邀请明细表';
+
+CREATE TABLE IF NOT EXISTS `credit_ledger` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '' COMMENT '积分账户, 用户名或 KOL 用户id',
+  `type` varchar(32) NOT NULL DEFAULT '' COMMENT 'mission, invite_commission, kol_commission, admin_adjustment, revocation, streak_bonus',
+  `ref_id` varchar(64) NOT NULL DEFAULT '' COMMENT '关联的记录id',
+  `amount` bigint(20) NOT NULL DEFAULT 0 COMMENT '变动积分, 扣减为负数',
+  `balance` bigint(20) NOT NULL DEFAULT 0 COMMENT '变动后的余额',
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `operator` varchar(128) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_credit_ledger_ref` (`username`, `type`, `ref_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='积分流水表';
+
+CREATE TABLE IF NOT EXISTS `credit_balance` (
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `balance` bigint(20) NOT NULL DEFAULT 0,
+  `mission_credits` bigint(20) NOT NULL DEFAULT 0,
+  `invite_credits` bigint(20) NOT NULL DEFAULT 0,
+  `kol_credits` bigint(20) NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`username`),
+  KEY `idx_balance` (`balance`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='积分余额表, 由积分流水汇总';
+
+CREATE TABLE IF NOT EXISTS `user_mission_recheck` (
+  `user_mission_id` bigint(20) NOT NULL,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `status` varchar(32) NOT NULL DEFAULT '' COMMENT 'passed, failing, revoked, flagged',
+  `reason` varchar(512) NOT NULL DEFAULT '' COMMENT '最近一次校验失败的原因',
+  `checked_at` datetime NOT NULL DEFAULT 0,
+  `failed_at` datetime NOT NULL DEFAULT 0 COMMENT '首次校验失败的时间, 宽限期从此开始',
+  `created_at` datetime NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT 0,
+  PRIMARY KEY (`user_mission_id`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='任务完成记录重新校验表';
+
+CREATE TABLE IF NOT EXISTS `user_notification` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `type` varchar(64) NOT NULL DEFAULT '',
+  `params` json DEFAULT NULL,
+  `is_read` tinyint(1) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户站内通知表';
+
+CREATE TABLE IF NOT EXISTS `user_streak` (
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `current_streak` bigint(20) NOT NULL DEFAULT 0 COMMENT '当前连续完成天数',
+  `longest_streak` bigint(20) NOT NULL DEFAULT 0 COMMENT '最长连续完成天数',
+  `last_day` varchar(10) NOT NULL DEFAULT '' COMMENT '最近一次完成的日期, 按配置的时区计算',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`username`, `mission_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='每日任务连续完成表';
+
+CREATE TABLE IF NOT EXISTS `season` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `name` varchar(128) NOT NULL DEFAULT '',
+  `name_cn` varchar(128) NOT NULL DEFAULT '',
+  `status` int(1) NOT NULL DEFAULT 1 COMMENT '1 正常, 2 已归档',
+  `start_time` datetime NOT NULL DEFAULT 0,
+  `end_time` datetime NOT NULL DEFAULT 0 COMMENT '不晚于开始时间时不限结束时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='赛季表';
+
+CREATE TABLE IF NOT EXISTS `season_credit` (
+  `season_id` bigint(20) NOT NULL DEFAULT 0,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `credits` bigint(20) NOT NULL DEFAULT 0,
+  `mission_credits` bigint(20) NOT NULL DEFAULT 0,
+  `invite_credits` bigint(20) NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`season_id`, `username`),
+  KEY `idx_season_credits` (`season_id`, `credits`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户赛季积分表';
+
+CREATE TABLE IF NOT EXISTS `translation` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `locale` varchar(16) NOT NULL DEFAULT '' COMMENT '语言, 如 en, cn, ko, vi, ru',
+  `msg_key` varchar(128) NOT NULL DEFAULT '' COMMENT '文案 key, 如 error.1001, mission.1001.title',
+  `text` text NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_locale_key` (`locale`, `msg_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='多语言文案表';
+
+CREATE TABLE IF NOT EXISTS `channel` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `code` varchar(28) NOT NULL DEFAULT '' COMMENT '对应 mission.channel',
+  `group_name` varchar(28) NOT NULL DEFAULT '' COMMENT '分组, 返回 <group>_missions 兼容旧版本',
+  `title` varchar(128) NOT NULL DEFAULT '',
+  `title_cn` varchar(128) NOT NULL DEFAULT '',
+  `icon` varchar(255) NOT NULL DEFAULT '',
+  `sort_id` int(4) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='任务渠道表';
+
+insert into channel(code, group_name, title, title_cn, sort_id) values
+('Wallet', 'basic', 'Wallet', '钱包', 1),
+('Titan', 'basic', 'Titan', 'Titan', 2),
+('Twitter', 'twitter', 'Twitter', '推特', 3),
+('Discord', 'discord', 'Discord', 'Discord', 4),
+('Telegram', 'telegram', 'Telegram', '电报', 5);
+
+CREATE TABLE IF NOT EXISTS `mission_submission` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `period` varchar(128) NOT NULL DEFAULT '' COMMENT '提交时任务所处的周期',
+  `urls` json DEFAULT NULL,
+  `content` text NOT NULL,
+  `status` int(1) NOT NULL DEFAULT 0 COMMENT '0 待审核, 1 已通过, 2 已拒绝',
+  `reason` varchar(512) NOT NULL DEFAULT '' COMMENT '拒绝原因',
+  `reviewer` varchar(255) NOT NULL DEFAULT '',
+  `user_mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `reviewed_at` datetime NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_username_mission` (`username`, `mission_id`, `period`),
+  KEY `idx_status` (`status`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='人工审核任务提交表';
+
+CREATE TABLE IF NOT EXISTS `quiz_question` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `question` varchar(1024) NOT NULL DEFAULT '',
+  `question_cn` varchar(1024) NOT NULL DEFAULT '',
+  `options` json DEFAULT NULL COMMENT '选项, [{"key":"A","text":"","text_cn":""}]',
+  `answer` varchar(255) NOT NULL DEFAULT '' COMMENT '正确选项的 key, 多选题用逗号分隔',
+  `sort_id` int(11) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_mission` (`mission_id`, `sort_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='答题任务题目表';
+
+CREATE TABLE IF NOT EXISTS `quiz_attempt` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `period` varchar(128) NOT NULL DEFAULT '' COMMENT '答题时任务所处的周期',
+  `score` int(11) NOT NULL DEFAULT 0 COMMENT '得分, 百分制',
+  `passed` tinyint(1) NOT NULL DEFAULT 0,
+  `answers` json DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_username_mission` (`username`, `mission_id`, `period`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='答题记录表';
+
+CREATE TABLE IF NOT EXISTS `tracking_visit` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '' COMMENT '追踪链接所属的用户',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `period` varchar(128) NOT NULL DEFAULT '' COMMENT '访问时任务所处的周期',
+  `ip` varchar(64) NOT NULL DEFAULT '',
+  `fingerprint` varchar(64) NOT NULL DEFAULT '',
+  `user_agent` varchar(512) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_ip` (`username`, `mission_id`, `period`, `ip`),
+  UNIQUE KEY `uniq_fingerprint` (`username`, `mission_id`, `period`, `fingerprint`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='追踪链接访问记录表';
+
+CREATE TABLE IF NOT EXISTS `github_oauth` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `state` varchar(64) NOT NULL DEFAULT '',
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `github_user_id` varchar(64) NOT NULL DEFAULT '',
+  `github_login` varchar(255) NOT NULL DEFAULT '',
+  `access_token` varchar(255) NOT NULL DEFAULT '',
+  `redirect_uri` varchar(255) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_state` (`state`),
+  KEY `idx_username` (`username`),
+  KEY `idx_github_user_id` (`github_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='GitHub 授权表';
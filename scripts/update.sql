@@ -8,4 +8,326 @@ alter table mission add column `sort_id`  int(4) NOT NULL DEFAULT 0 after title_
 
 
 alter table users add column  `from_kol_ref_code` varchar(64) NOT NULL DEFAULT '';
-alter table users add column  `from_kol_user_id` varchar(64) NOT NULL DEFAULT '';
\ No newline at end of file
+alter table users add column  `from_kol_user_id` varchar(64) NOT NULL DEFAULT '';
+
+alter table mission add column `verifier` varchar(64) NOT NULL DEFAULT '' after target_id;
+alter table sub_mission add column `verifier` varchar(64) NOT NULL DEFAULT '' after target_id;
+
+update mission set verifier = 'follow_twitter' where id = 1002;
+update mission set verifier = 'retweet' where id = 1003;
+update mission set verifier = 'like_tweet' where id = 1004;
+update mission set verifier = 'join_discord' where id = 1005;
+update mission set verifier = 'join_telegram' where id in (1006, 1013);
+update mission set verifier = 'binding_kol' where id = 1007;
+update mission set verifier = 'visit_official_website' where id = 1008;
+update mission set verifier = 'visit_referrer_page' where id = 1009;
+update mission set verifier = 'join_discord_channel' where id = 1012;
+update mission set verifier = 'quote_tweet' where id = 1106;
+update mission set verifier = 'post_tweet' where id = 1107;
+update mission set verifier = 'invite_friends_to_discord' where id = 1108;
+
+
+alter table mission add column `params` json DEFAULT NULL after verifier;
+alter table sub_mission add column `params` json DEFAULT NULL after verifier;
+
+update mission set params = json_object('tweet_id', substring_index(substring_index(open_url, 'tweet_id=', -1), '&', 1))
+    where verifier in ('retweet', 'like_tweet', 'quote_tweet') and open_url like '%tweet_id=%';
+update mission set params = json_set(params, '$.required_mentions', 3) where verifier = 'quote_tweet';
+update mission set params = json_object('target_channel', target_id) where verifier in ('join_telegram', 'join_discord_channel');
+-- post_tweet 原来要求推文首行包含在 open_url 的 text 参数中, 迁移为 required_text (text 参数的首行).
+-- text 中的常见转义字符按 url 解码, 含有其他转义字符 (如中文、emoji) 时需要手动核对 required_text
+update mission set params = json_object('required_text', trim(substring_index(
+    replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+        substring_index(substring_index(open_url, 'text=', -1), '&', 1),
+        '+', ' '), '%20', ' '), '%23', '#'), '%40', '@'), '%2C', ','), '%2c', ','), '%21', '!'), '%3A', ':'), '%3a', ':'), '%2F', '/'), '%2f', '/'), '%3F', '?'), '%3f', '?'), '%0A', '\n'), '%0a', '\n'), '%25', '%'),
+    '\n', 1)))
+    where verifier = 'post_tweet' and (open_url like '%?text=%' or open_url like '%&text=%');
+-- 没有 text 参数的 post_tweet 任务需要手动配置 required_text / required_hashtags, 未配置时不予校验, 例如:
+-- update mission set params = '{"required_text": "...", "required_hashtags": ["TitanNetwork"]}' where id = 1107;
+
+
+CREATE TABLE `mission_prerequisite` (
+`id` bigint(20) NOT NULL AUTO_INCREMENT,
+`mission_id` bigint(20) NOT NULL DEFAULT 0,
+`prerequisite_id` bigint(20) NOT NULL DEFAULT 0,
+`created_at` datetime NOT NULL DEFAULT 0,
+PRIMARY KEY (`id`),
+UNIQUE KEY `uniq_mission_prerequisite` (`mission_id`, `prerequisite_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 转推需要先关注推特, 志愿者任务需要先成为预备志愿者
+insert into mission_prerequisite(mission_id, prerequisite_id, created_at) values
+    (1003, 1002, now()),
+    (1012, 1011, now()),
+    (1013, 1011, now());
+
+
+alter table mission add column `recurrence` varchar(64) NOT NULL DEFAULT '' after type;
+alter table sub_mission add column `recurrence` varchar(64) NOT NULL DEFAULT '' after type;
+
+
+-- 任务完成记录的周期标识: 一次性任务为空, 周期任务为周期开始时间, 按推文校验的任务追加推文链接
+alter table user_mission add column `period` varchar(255) NOT NULL DEFAULT '' after content;
+
+update user_mission set period = date_format(created_at, '%Y-%m-%d 00:00:00') where type = 2;
+update user_mission set period = date_format(date_sub(date(created_at), interval dayofweek(created_at) - 1 day), '%Y-%m-%d 00:00:00') where type = 3;
+update user_mission um join mission m on um.mission_id = m.id set um.period = concat(um.period, '#', um.content)
+    where m.verifier in ('retweet', 'like_tweet') and um.sub_mission_id = 0;
+
+-- 清理并发校验产生的重复记录, 保留最早的一条
+delete t1 from user_mission t1 join user_mission t2
+    on t1.username = t2.username and t1.mission_id = t2.mission_id and t1.sub_mission_id = t2.sub_mission_id and t1.period = t2.period and t1.id > t2.id;
+
+alter table user_mission add unique key `uniq_user_mission_period` (`username`, `mission_id`, `sub_mission_id`, `period`);
+
+
+CREATE TABLE IF NOT EXISTS `credit_ledger` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '' COMMENT '积分账户, 用户名或 KOL 用户id',
+  `type` varchar(32) NOT NULL DEFAULT '' COMMENT 'mission, invite_commission, kol_commission, admin_adjustment, revocation, streak_bonus',
+  `ref_id` varchar(64) NOT NULL DEFAULT '' COMMENT '关联的记录id',
+  `amount` bigint(20) NOT NULL DEFAULT 0 COMMENT '变动积分, 扣减为负数',
+  `balance` bigint(20) NOT NULL DEFAULT 0 COMMENT '变动后的余额',
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `operator` varchar(128) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_credit_ledger_ref` (`username`, `type`, `ref_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='积分流水表';
+
+CREATE TABLE IF NOT EXISTS `credit_balance` (
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `balance` bigint(20) NOT NULL DEFAULT 0,
+  `mission_credits` bigint(20) NOT NULL DEFAULT 0,
+  `invite_credits` bigint(20) NOT NULL DEFAULT 0,
+  `kol_credits` bigint(20) NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`username`),
+  KEY `idx_balance` (`balance`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='积分余额表, 由积分流水汇总';
+
+-- 根据历史任务完成记录及邀请记录生成积分流水
+insert into credit_ledger(username, type, ref_id, amount, reason, created_at)
+    select username, 'mission', id, credit, concat('complete mission ', mission_id), created_at from user_mission order by id;
+insert into credit_ledger(username, type, ref_id, amount, reason, created_at)
+    select username, 'invite_commission', id, credit, concat(invited_name, ' completed mission ', mission_id), created_at from invite_log order by id;
+insert into credit_ledger(username, type, ref_id, amount, reason, created_at)
+    select u.from_kol_user_id, 'kol_commission', m.id, floor(m.credit * 20 / 100), concat(m.username, ' completed mission ', m.mission_id), m.created_at
+    from user_mission m join users u on m.username = u.username where u.from_kol_user_id <> '' and floor(m.credit * 20 / 100) > 0 order by m.id;
+
+update credit_ledger l join (
+    select id, sum(amount) over (partition by username order by id) as running from credit_ledger
+) r on l.id = r.id set l.balance = r.running;
+
+insert into credit_balance(username, balance, mission_credits, invite_credits, kol_credits, updated_at)
+    select username, sum(amount),
+        sum(if(type = 'mission', amount, 0)),
+        sum(if(type = 'invite_commission', amount, 0)),
+        sum(if(type = 'kol_commission', amount, 0)),
+        now()
+    from credit_ledger group by username;
+
+-- 邀请分成关联被邀请人的任务完成记录, 撤销任务时一并撤销分成
+ALTER TABLE `invite_log` ADD COLUMN `user_mission_id` bigint(20) NOT NULL DEFAULT 0 COMMENT '被邀请人的任务完成记录' AFTER `credit`, ADD KEY `idx_user_mission_id` (`user_mission_id`);
+
+-- 历史邀请记录在完成记录写入后生成, 按被邀请人、任务及时间关联最近的一条完成记录
+update invite_log il set il.user_mission_id = ifnull((
+    select m.id from user_mission m
+    where m.username = il.invited_name and m.mission_id = il.mission_id
+    and m.created_at between il.created_at - interval 1 minute and il.created_at
+    order by m.created_at desc, m.id desc limit 1
+), 0) where il.user_mission_id = 0;
+
+CREATE TABLE IF NOT EXISTS `user_mission_recheck` (
+  `user_mission_id` bigint(20) NOT NULL,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `status` varchar(32) NOT NULL DEFAULT '' COMMENT 'passed, failing, revoked, flagged',
+  `reason` varchar(512) NOT NULL DEFAULT '' COMMENT '最近一次校验失败的原因',
+  `checked_at` datetime NOT NULL DEFAULT 0,
+  `failed_at` datetime NOT NULL DEFAULT 0 COMMENT '首次校验失败的时间, 宽限期从此开始',
+  `created_at` datetime NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT 0,
+  PRIMARY KEY (`user_mission_id`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='任务完成记录重新校验表';
+
+CREATE TABLE IF NOT EXISTS `user_notification` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `type` varchar(64) NOT NULL DEFAULT '',
+  `params` json DEFAULT NULL,
+  `is_read` tinyint(1) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户站内通知表';
+
+-- 任务完成人次上限及积分预算
+alter table mission add column `max_completions` bigint(20) NOT NULL DEFAULT 0 after parent_id,
+    add column `credit_budget` bigint(20) NOT NULL DEFAULT 0 after max_completions,
+    add column `completions` bigint(20) NOT NULL DEFAULT 0 after credit_budget,
+    add column `credits_awarded` bigint(20) NOT NULL DEFAULT 0 after completions;
+alter table sub_mission add column `max_completions` bigint(20) NOT NULL DEFAULT 0 after parent_id,
+    add column `credit_budget` bigint(20) NOT NULL DEFAULT 0 after max_completions,
+    add column `completions` bigint(20) NOT NULL DEFAULT 0 after credit_budget,
+    add column `credits_awarded` bigint(20) NOT NULL DEFAULT 0 after completions;
+
+update mission m join (
+    select mission_id, count(*) as completions, sum(credit) as credits from user_mission where sub_mission_id = 0 group by mission_id
+) um on m.id = um.mission_id set m.completions = um.completions, m.credits_awarded = um.credits;
+update sub_mission m join (
+    select sub_mission_id, count(*) as completions, sum(credit) as credits from user_mission where sub_mission_id > 0 group by sub_mission_id
+) um on m.id = um.sub_mission_id set m.completions = um.completions, m.credits_awarded = um.credits;
+
+CREATE TABLE IF NOT EXISTS `user_streak` (
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `current_streak` bigint(20) NOT NULL DEFAULT 0 COMMENT '当前连续完成天数',
+  `longest_streak` bigint(20) NOT NULL DEFAULT 0 COMMENT '最长连续完成天数',
+  `last_day` varchar(10) NOT NULL DEFAULT '' COMMENT '最近一次完成的日期, 按配置的时区计算',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`username`, `mission_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='每日任务连续完成表';
+
+-- 赛季
+alter table mission add column `season_id` bigint(20) NOT NULL DEFAULT 0 after credits_awarded;
+alter table sub_mission add column `season_id` bigint(20) NOT NULL DEFAULT 0 after credits_awarded;
+alter table user_mission add column `season_id` bigint(20) NOT NULL DEFAULT 0 after period, add KEY `idx_season_id` (`season_id`);
+
+CREATE TABLE IF NOT EXISTS `season` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `name` varchar(128) NOT NULL DEFAULT '',
+  `name_cn` varchar(128) NOT NULL DEFAULT '',
+  `status` int(1) NOT NULL DEFAULT 1 COMMENT '1 正常, 2 已归档',
+  `start_time` datetime NOT NULL DEFAULT 0,
+  `end_time` datetime NOT NULL DEFAULT 0 COMMENT '不晚于开始时间时不限结束时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='赛季表';
+
+CREATE TABLE IF NOT EXISTS `season_credit` (
+  `season_id` bigint(20) NOT NULL DEFAULT 0,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `credits` bigint(20) NOT NULL DEFAULT 0,
+  `mission_credits` bigint(20) NOT NULL DEFAULT 0,
+  `invite_credits` bigint(20) NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`season_id`, `username`),
+  KEY `idx_season_credits` (`season_id`, `credits`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户赛季积分表';
+
+-- 多语言文案
+CREATE TABLE IF NOT EXISTS `translation` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `locale` varchar(16) NOT NULL DEFAULT '' COMMENT '语言, 如 en, cn, ko, vi, ru',
+  `msg_key` varchar(128) NOT NULL DEFAULT '' COMMENT '文案 key, 如 error.1001, mission.1001.title',
+  `text` text NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_locale_key` (`locale`, `msg_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='多语言文案表';
+
+-- 任务渠道
+CREATE TABLE IF NOT EXISTS `channel` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `code` varchar(28) NOT NULL DEFAULT '' COMMENT '对应 mission.channel',
+  `group_name` varchar(28) NOT NULL DEFAULT '' COMMENT '分组, 返回 <group>_missions 兼容旧版本',
+  `title` varchar(128) NOT NULL DEFAULT '',
+  `title_cn` varchar(128) NOT NULL DEFAULT '',
+  `icon` varchar(255) NOT NULL DEFAULT '',
+  `sort_id` int(4) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='任务渠道表';
+
+insert into channel(code, group_name, title, title_cn, sort_id) values
+('Wallet', 'basic', 'Wallet', '钱包', 1),
+('Titan', 'basic', 'Titan', 'Titan', 2),
+('Twitter', 'twitter', 'Twitter', '推特', 3),
+('Discord', 'discord', 'Discord', 'Discord', 4),
+('Telegram', 'telegram', 'Telegram', '电报', 5);
+
+-- 人工审核任务
+CREATE TABLE IF NOT EXISTS `mission_submission` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `period` varchar(128) NOT NULL DEFAULT '' COMMENT '提交时任务所处的周期',
+  `urls` json DEFAULT NULL,
+  `content` text NOT NULL,
+  `status` int(1) NOT NULL DEFAULT 0 COMMENT '0 待审核, 1 已通过, 2 已拒绝',
+  `reason` varchar(512) NOT NULL DEFAULT '' COMMENT '拒绝原因',
+  `reviewer` varchar(255) NOT NULL DEFAULT '',
+  `user_mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `reviewed_at` datetime NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_username_mission` (`username`, `mission_id`, `period`),
+  KEY `idx_status` (`status`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='人工审核任务提交表';
+
+-- 答题任务
+CREATE TABLE IF NOT EXISTS `quiz_question` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `question` varchar(1024) NOT NULL DEFAULT '',
+  `question_cn` varchar(1024) NOT NULL DEFAULT '',
+  `options` json DEFAULT NULL COMMENT '选项, [{"key":"A","text":"","text_cn":""}]',
+  `answer` varchar(255) NOT NULL DEFAULT '' COMMENT '正确选项的 key, 多选题用逗号分隔',
+  `sort_id` int(11) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_mission` (`mission_id`, `sort_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='答题任务题目表';
+
+CREATE TABLE IF NOT EXISTS `quiz_attempt` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `period` varchar(128) NOT NULL DEFAULT '' COMMENT '答题时任务所处的周期',
+  `score` int(11) NOT NULL DEFAULT 0 COMMENT '得分, 百分制',
+  `passed` tinyint(1) NOT NULL DEFAULT 0,
+  `answers` json DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_username_mission` (`username`, `mission_id`, `period`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='答题记录表';
+
+-- 访问任务的追踪链接
+CREATE TABLE IF NOT EXISTS `tracking_visit` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `username` varchar(255) NOT NULL DEFAULT '' COMMENT '追踪链接所属的用户',
+  `mission_id` bigint(20) NOT NULL DEFAULT 0,
+  `period` varchar(128) NOT NULL DEFAULT '' COMMENT '访问时任务所处的周期',
+  `ip` varchar(64) NOT NULL DEFAULT '',
+  `fingerprint` varchar(64) NOT NULL DEFAULT '',
+  `user_agent` varchar(512) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_ip` (`username`, `mission_id`, `period`, `ip`),
+  UNIQUE KEY `uniq_fingerprint` (`username`, `mission_id`, `period`, `fingerprint`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='追踪链接访问记录表';
+
+-- GitHub 授权
+CREATE TABLE IF NOT EXISTS `github_oauth` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `state` varchar(64) NOT NULL DEFAULT '',
+  `username` varchar(255) NOT NULL DEFAULT '',
+  `github_user_id` varchar(64) NOT NULL DEFAULT '',
+  `github_login` varchar(255) NOT NULL DEFAULT '',
+  `access_token` varchar(255) NOT NULL DEFAULT '',
+  `redirect_uri` varchar(255) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_state` (`state`),
+  KEY `idx_username` (`username`),
+  KEY `idx_github_user_id` (`github_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='GitHub 授权表';